@@ -0,0 +1,210 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/nexidian/gocliselect"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+const BROWSE_PAGE_SIZE = 15
+
+// browse lists the files of the destination page by page on a terminal,
+// showing the metadata of the chosen one and offering to download it, delete
+// it or copy a presigned link to it. New files can be uploaded too. The files
+// are listed once, and again only after an upload.
+func browse(args []string) {
+	flags := newFlagSet("browse")
+	expiry := flags.Duration("expiry", 24*time.Hour, "Validity of the presigned links")
+	parseFlags(flags, args)
+
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		fmt.Println("browse needs a terminal")
+		os.Exit(1)
+	}
+
+	dest := openDestination()
+	ctx := context.Background()
+
+	files, err := browseList(ctx, dest)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	page := 0
+	for {
+		pages := max((len(files)+BROWSE_PAGE_SIZE-1)/BROWSE_PAGE_SIZE, 1)
+		page = min(page, pages-1)
+
+		menu := gocliselect.NewMenu(fmt.Sprintf("%s: %d file(s), page %d of %d", dest.Name, len(files), page+1, pages))
+		for i := page * BROWSE_PAGE_SIZE; i < min((page+1)*BROWSE_PAGE_SIZE, len(files)); i++ {
+			f := files[i]
+			menu.AddItem(fmt.Sprintf("%-40s %10s  %s", f.Key, formatBytes(f.Size), f.LastModified.Local().Format("2006-01-02 15:04")), strconv.Itoa(i))
+		}
+		if page < pages-1 {
+			menu.AddItem("Next page", "next")
+		}
+		if page > 0 {
+			menu.AddItem("Previous page", "previous")
+		}
+		menu.AddItem("Upload a file", "upload")
+		menu.AddItem("Quit", "quit")
+
+		switch choice := menu.Display(); choice {
+		case "next":
+			page++
+		case "previous":
+			page--
+		case "upload":
+			if !browseUpload(ctx, dest) {
+				continue
+			}
+
+			if files, err = browseList(ctx, dest); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		case "", "quit":
+			return
+		default:
+			i, _ := strconv.Atoi(choice)
+			if browseFile(ctx, dest, files[i].Key, *expiry) {
+				files = slices.Delete(files, i, i+1)
+			}
+		}
+	}
+}
+
+// browseList returns the files of the destination, the newest first, with
+// keys relative to its prefix.
+func browseList(ctx context.Context, dest config.Destination) ([]minio.ObjectInfo, error) {
+	list, err := minioClient.List(ctx, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range list {
+		list[i].Key = strings.TrimPrefix(strings.TrimPrefix(list[i].Key, dest.Prefix), "/")
+	}
+	slices.SortFunc(list, func(a, b minio.ObjectInfo) int {
+		return b.LastModified.Compare(a.LastModified)
+	})
+
+	return list, nil
+}
+
+// browseFile shows the metadata of the file and runs the chosen action,
+// telling if the file was deleted.
+func browseFile(ctx context.Context, dest config.Destination, key string, expiry time.Duration) bool {
+	info, err := minioClient.Stat(ctx, dest, key)
+	if err != nil {
+		fmt.Println(err)
+
+		return false
+	}
+
+	fmt.Printf("\nKey:           %s\nSize:          %s (%d bytes)\nLast modified: %s\nContent type:  %s\n", key, formatBytes(info.Size), info.Size, info.LastModified.Local().Format(time.DateTime), info.ContentType)
+	for _, k := range slices.Sorted(maps.Keys(info.UserMetadata)) {
+		fmt.Printf("%-15s%s\n", strings.TrimPrefix(k, "X-Amz-Meta-")+":", info.UserMetadata[k])
+	}
+	fmt.Println()
+
+	menu := gocliselect.NewMenu("Action")
+	menu.AddItem("Download", "download")
+	menu.AddItem("Copy presigned link", "link")
+	menu.AddItem("Delete", "delete")
+	menu.AddItem("Back", "back")
+
+	switch menu.Display() {
+	case "download":
+		path, err := readLine(fmt.Sprintf("Save as [%s]: ", filepath.Base(key)))
+		if err != nil {
+			fmt.Println(err)
+
+			return false
+		}
+
+		path = cmp.Or(path, filepath.Base(key))
+		if err := download(ctx, dest, key, path); err != nil {
+			fmt.Println(err)
+
+			return false
+		}
+		fmt.Printf("Saved at %s\n", path)
+	case "link":
+		link, err := minioClient.PresignedURL(ctx, dest, key, expiry)
+		if err != nil {
+			fmt.Println(err)
+
+			return false
+		}
+		// OSC 52 sets the clipboard of the terminal, even through SSH
+		fmt.Printf("\033]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(link.String())))
+		fmt.Printf("Link copied to the clipboard (valid for %s):\n%s\n", expiry, link)
+	case "delete":
+		if !confirm(fmt.Sprintf("Delete %q?", key)) {
+			return false
+		}
+
+		if err := minioClient.Delete(ctx, dest, key); err != nil {
+			fmt.Println(err)
+
+			return false
+		}
+		fmt.Printf("Deleted %s\n", key)
+
+		return true
+	}
+
+	return false
+}
+
+// browseUpload asks for a file and its fields and uploads it, telling if it
+// was uploaded.
+func browseUpload(ctx context.Context, dest config.Destination) bool {
+	path, err := readLine("File to upload: ")
+	if err != nil || path == "" {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Println(err)
+
+		return false
+	}
+
+	p := maps.Clone(params)
+	if err := fillParams(dest, p, true); err != nil {
+		fmt.Println(err)
+
+		return false
+	}
+
+	progress := startProgress(info.Size())
+	results := minioClient.UploadMultiple(ctx, dest, []string{path}, []map[string]string{p}, 1, progress.transfer())
+	progress.stop()
+
+	if err := results[0].Error; err != nil {
+		fmt.Printf("FAIL\t%s\t%v\n", path, err)
+
+		return false
+	}
+	fmt.Printf("OK\t%s\n", path)
+
+	return true
+}
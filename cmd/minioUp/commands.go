@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/check"
+)
+
+type destinationItem struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Bucket string `json:"bucket,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+func destinations(args []string) {
+	flags := newFlagSet("destinations")
+	onlyNames := flags.Bool("names", false, "Print only the names")
+	parseFlags(flags, args)
+
+	printDestinations(loadConfig().Destinations, *onlyNames)
+}
+
+func printDestinations(dests []config.Destination, onlyNames bool) {
+	items := make([]destinationItem, 0, len(dests))
+	for _, d := range dests {
+		items = append(items, destinationItem{d.ID, d.Name, d.Bucket, d.Prefix})
+	}
+
+	if *jsonOutput {
+		printJSON(items)
+
+		return
+	}
+
+	for _, item := range items {
+		if onlyNames || item.Bucket == "" {
+			fmt.Println(item.Name)
+
+			continue
+		}
+		fmt.Printf("%s\t%s\n", item.Name, strings.TrimSuffix(item.Bucket+"/"+item.Prefix, "/"))
+	}
+}
+
+func configCmd(args []string) {
+	flags := newFlagSet("config")
+	connect := flags.Bool("connect", false, "Check if the buckets exist and the SMTP server is reachable")
+	parseFlags(flags, args)
+
+	switch {
+	case (flags.Arg(0) == "schema" || flags.Arg(0) == "fragment-schema") && flags.NArg() == 1:
+		if err := check.WriteSchema(os.Stdout, flags.Arg(0) == "fragment-schema"); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	case flags.Arg(0) != "validate" || flags.NArg() > 1:
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if !check.ConfigFile(os.Stdout, configPath(), *connect) {
+		os.Exit(1)
+	}
+}
+
+// completion prints the script of a shell completing the commands, the flags
+// and the names of the destinations (by the destinations command).
+func completion(args []string) {
+	flags := newFlagSet("completion")
+	parseFlags(flags, args)
+
+	prog := filepath.Base(os.Args[0])
+	cmds := strings.Join(slices.Sorted(maps.Keys(commands)), " ")
+	flagNames := []string{}
+	flag.VisitAll(func(f *flag.Flag) {
+		flagNames = append(flagNames, "-"+f.Name)
+	})
+
+	switch flags.Arg(0) {
+	case "bash":
+		fmt.Printf(bashCompletion, prog, cmds, strings.Join(flagNames, " "))
+	case "zsh":
+		fmt.Println("autoload -U +X bashcompinit && bashcompinit")
+		fmt.Printf(bashCompletion, prog, cmds, strings.Join(flagNames, " "))
+	case "fish":
+		fmt.Printf("complete -c %s -n __fish_use_subcommand -a %q\n", prog, cmds)
+		fmt.Printf("complete -c %s -o d -x -a '(%[1]s destinations -names 2>/dev/null)'\n", prog)
+		flag.VisitAll(func(f *flag.Flag) {
+			if f.Name != "d" {
+				fmt.Printf("complete -c %s -o %s -d '%s'\n", prog, f.Name, strings.ReplaceAll(f.Usage, "'", `\'`))
+			}
+		})
+	default:
+		flags.Usage()
+		os.Exit(1)
+	}
+}
+
+const bashCompletion = `_%[1]s() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+	case "$prev" in
+		-d) COMPREPLY=($(compgen -W "$(%[1]s destinations -names 2>/dev/null)" -- "$cur")); return ;;
+		-profile) COMPREPLY=($(compgen -W "$(ls "${XDG_CONFIG_HOME:-$HOME/.config}/minioUp" 2>/dev/null | sed 's/\.yml$//')" -- "$cur")); return ;;
+		-c|-manifest|-params-file) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+	esac
+	if [[ "$cur" == -* ]]; then
+		COMPREPLY=($(compgen -W "%[3]s" -- "$cur"))
+	else
+		COMPREPLY=($(compgen -W "%[2]s" -f -- "$cur"))
+	fi
+}
+complete -o filenames -F _%[1]s %[1]s
+`
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+// get downloads an object to a local path. The content is written to a
+// ".part" file first, so an interrupted download is resumed on the next run
+// (if the object wasn't replaced).
+func get(args []string) {
+	flags := newFlagSet("get")
+	parseFlags(flags, args)
+
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	key := flags.Arg(0)
+	path := filepath.Base(key)
+	if flags.NArg() == 2 {
+		path = flags.Arg(1)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			path = filepath.Join(path, filepath.Base(key))
+		}
+	}
+
+	if err := download(context.Background(), openDestination(), key, path); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Done!")
+}
+
+// download writes the object to path. The ETag of the object is kept beside
+// the ".part" file, so a download is only resumed if the object is the same;
+// otherwise, or if it's replaced meanwhile, the download starts over.
+func download(ctx context.Context, dest config.Destination, key, path string) error {
+	err := tryDownload(ctx, dest, key, path)
+	if minioClient.IsChanged(err) {
+		fmt.Println("The object has changed, downloading it again…")
+		err = tryDownload(ctx, dest, key, path)
+	}
+
+	return err
+}
+
+func tryDownload(ctx context.Context, dest config.Destination, key, path string) error {
+	info, err := minioClient.Stat(ctx, dest, key)
+	if err != nil {
+		return err
+	}
+
+	partPath, etagPath := path+".part", path+".part.etag"
+	var offset int64
+	if part, err := os.Stat(partPath); err == nil && part.Size() > 0 && part.Size() <= info.Size {
+		if etag, err := os.ReadFile(filepath.Clean(etagPath)); err == nil && string(etag) == info.ETag {
+			offset = part.Size()
+			fmt.Printf("Resuming download at %d of %d bytes…\n", offset, info.Size)
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	} else if err := os.WriteFile(etagPath, []byte(info.ETag), 0o600); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Clean(partPath), flags, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if offset < info.Size {
+		obj, err := minioClient.GetFrom(ctx, dest, key, offset, info.ETag)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = obj.Close() }()
+
+		if _, err := io.Copy(f, obj); err != nil {
+			if minioClient.IsChanged(err) {
+				_ = os.Remove(partPath)
+				_ = os.Remove(etagPath)
+
+				return err
+			}
+
+			return fmt.Errorf("error downloading %s (run again to resume): %w", key, err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := verify(partPath, info.Size, info.ETag); err != nil {
+		_ = os.Remove(partPath)
+		_ = os.Remove(etagPath)
+
+		return err
+	}
+	_ = os.Remove(etagPath)
+
+	return os.Rename(partPath, path)
+}
+
+// verify checks the size of the file and its MD5 when the ETag has one, which
+// is not the case for multipart uploads.
+func verify(path string, size int64, etag string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.Size() != size {
+		return fmt.Errorf("size mismatch: expected %d bytes, got %d", size, info.Size())
+	}
+
+	if strings.Contains(etag, "-") {
+		return nil
+	}
+
+	sum, err := md5sum(path)
+	if err != nil {
+		return err
+	}
+
+	if sum != etag {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", etag, sum)
+	}
+
+	return nil
+}
+
+func rm(args []string) {
+	flags := newFlagSet("rm")
+	force := flags.Bool("f", false, "Don't ask for confirmation")
+	parseFlags(flags, args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	keys := flags.Args()
+	dest := openDestination()
+
+	if !*force && isTerminal(os.Stdin) && !confirm(fmt.Sprintf("Delete %d file(s) from %q?", len(keys), dest.Name)) {
+		os.Exit(0)
+	}
+
+	failures := minioClient.DeleteMultiple(context.Background(), dest, keys)
+	for _, key := range keys {
+		if err := failures[key]; err != nil {
+			fmt.Printf("FAIL\t%s\t%v\n", key, err)
+
+			continue
+		}
+		fmt.Printf("OK\t%s\n", key)
+	}
+
+	if len(failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+func confirm(question string) bool {
+	answer, _ := readLine(question + " [y/N] ")
+	answer = strings.ToLower(answer)
+
+	return answer == "y" || answer == "yes"
+}
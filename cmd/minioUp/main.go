@@ -3,11 +3,16 @@ package main
 import (
 	"cmp"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/nexidian/gocliselect"
 
@@ -16,21 +21,165 @@ import (
 )
 
 var (
-	onlyListing = flag.Bool("l", false, "Only list files (no upload)")
-	configFile  = flag.String("c", "config.yml", "Config file")
+	onlyListing  = flag.Bool("l", false, "Only list files (same as the ls command)")
+	checkMirrors = flag.Bool("m", false, "Report differences between the destination and its mirrors (same as the mirrors command)")
+	configFile   = flag.String("c", "", "Config file (default: $MINIOUP_CONFIG, config.yml or the profile on the user config directory)")
+	profile      = flag.String("profile", "", "Name of the config on the user config directory (default: $MINIOUP_PROFILE)")
+	destName     = flag.String("d", "", "Name or ID of the destination (required without a terminal if there are many)")
+	jsonOutput   = flag.Bool("json", false, "Print upload results and listings as JSON")
+	concurrency  = flag.Int("j", 4, "Number of simultaneous uploads")
+	manifest     = flag.String("manifest", "", "JSON file mapping each file to its params")
+	paramsFile   = flag.String("params-file", "", "JSON or YAML file with params applied to every file (\"-p\" ones take precedence)")
+	dryRun       = flag.Bool("dry-run", false, "Only validate the uploads and show where the files would be written")
+	remoteURL    = flag.String("remote", "", "URL of a minioUp server to use instead of S3 (API token on $MINIOUP_TOKEN)")
+	retries      = flag.Int("retries", 3, "Number of retries of an upload after a transient error")
+	resume       = flag.Bool("resume", false, "Send files over 16 MiB one part at a time, so a retry resumes from the first part not sent")
+	params       = paramsFlag{}
+
+	commands map[string]command
 )
 
+type command struct {
+	run   func(args []string)
+	usage string
+}
+
+func init() {
+	flag.Var(params, "p", "Param as key=value applied to every file (repeatable)")
+	flag.Var(params, "param", "Same as -p")
+
+	commands = map[string]command{
+		"upload":       {upload, "[-d destination] [-json] [-dry-run] [-j 4] [-retries 3] [-resume] [-p param=value…] [-params-file params.yml] [-manifest params.json] <file|glob>… | - -name <filename>"},
+		"ls":           {list, "[-d destination] [-json]"},
+		"browse":       {browse, "[-d destination] [-expiry 24h]"},
+		"get":          {get, "[-d destination] <key> [dest-path]"},
+		"rm":           {rm, "[-d destination] [-f] <key>…"},
+		"sync":         {syncDir, "[-d destination] [-j 4] [-p param=value…] [-delete] [-dry-run] <dir>"},
+		"watch":        {watch, "[-d destination] [-p param=value…] [-pattern regex] [-settle 2s] (-move-to <dir>|-delete) <dir>"},
+		"mirrors":      {checkMirror, "[-d destination]"},
+		"destinations": {destinations, "[-json] [-names]"},
+		"config":       {configCmd, "validate [-connect] | schema | fragment-schema"},
+		"completion":   {completion, "bash|zsh|fish"},
+	}
+
+	flag.Usage = usage
+}
+
 func usage() {
-	fmt.Fprintf(os.Stderr, "Usage of %s:\n%[1]s [-c config.yml] <file1> <param1> <value1> <param2> <value2>…\nor\n%[1]s -l [-c config.yml]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+	for _, name := range slices.Sorted(maps.Keys(commands)) {
+		fmt.Fprintf(os.Stderr, "  %s [-c config.yml | -profile name] [-remote url] %s %s\n", os.Args[0], name, commands[name].usage)
+	}
+	fmt.Fprintf(os.Stderr, "  %s <file> <param1> <value1> <param2> <value2>… (legacy upload)\n\nGlobal flags (accepted by every command):\n", os.Args[0])
+	flag.PrintDefaults()
 }
 
 func main() {
 	flag.Parse()
 
+	if *paramsFile != "" {
+		if err := loadParamsFile(*paramsFile); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	name, args := commandOf(flag.Args())
+	if *remoteURL != "" && name != "completion" {
+		remote(name, args)
+
+		return
+	}
+
+	commands[name].run(args)
+}
+
+// commandOf returns the command named by the first argument. The flags "-l"
+// and "-m" and a list of files without a command are the older forms of ls,
+// mirrors and upload.
+func commandOf(args []string) (string, []string) {
+	switch {
+	case *onlyListing:
+		return "ls", args
+	case *checkMirrors:
+		return "mirrors", args
+	case len(args) == 0 && *manifest == "":
+		usage()
+		os.Exit(1)
+	case len(args) > 0 && args[0] == "-":
+		return "upload", args
+	}
+
+	if len(args) > 0 {
+		if _, ok := commands[args[0]]; ok {
+			return args[0], args[1:]
+		}
+	}
+
+	return "upload", args
+}
+
+// newFlagSet returns the flag set of a command, which accepts the global
+// flags too (after its own ones are defined).
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s %s:\n  %[1]s %[2]s %s\n", os.Args[0], name, commands[name].usage)
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+// parseFlags adds the global flags missing on flags and parses args.
+func parseFlags(flags *flag.FlagSet, args []string) {
+	flag.VisitAll(func(f *flag.Flag) {
+		if flags.Lookup(f.Name) == nil {
+			flags.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	_ = flags.Parse(args)
+}
+
+// configPath finds the config file by "-c", "-profile", $MINIOUP_CONFIG,
+// $MINIOUP_PROFILE, "config.yml" on the current directory and, at last,
+// "config.yml" on the user config directory ($XDG_CONFIG_HOME/minioUp).
+// Profiles are the files "<name>.yml" of that directory.
+func configPath() string {
+	if *configFile != "" {
+		return *configFile
+	}
+
+	name := *profile
+	if name == "" {
+		if env := os.Getenv("MINIOUP_CONFIG"); env != "" {
+			return env
+		}
+		name = os.Getenv("MINIOUP_PROFILE")
+	}
+
+	if name == "" {
+		if _, err := os.Stat("config.yml"); err == nil {
+			return "config.yml"
+		}
+		name = "config"
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "config.yml"
+	}
+
+	return filepath.Join(dir, "minioUp", name+".yml")
+}
+
+func loadConfig() config.Config {
+	path := configPath()
+
 	cfg := config.Config{}
-	if err := cfg.Parse(*configFile); err != nil {
+	if err := cfg.Parse(path); err != nil {
 		if os.IsNotExist(err) {
-			fmt.Println("config file not found\nExample at: https://github.com/hitalos/minioUp")
+			fmt.Printf("config file not found: %s\nExample at: https://github.com/hitalos/minioUp\n", path)
 			os.Exit(1)
 		}
 
@@ -43,15 +192,18 @@ func main() {
 		os.Exit(1)
 	}
 
-	dest := cfg.Destinations[0]
+	return cfg
+}
 
-	if isTerminal(os.Stdin) && len(cfg.Destinations) > 1 {
-		destIdx := chooseDestination(cfg.Destinations)
-		qtd := len(cfg.Destinations)
-		if qtd < 255 && destIdx >= uint8(qtd) {
-			os.Exit(0)
-		}
-		dest = cfg.Destinations[destIdx]
+// openDestination loads the config, selects the destination and connects to
+// its server. Commands call it after parsing their flags.
+func openDestination() config.Destination {
+	cfg := loadConfig()
+
+	dest, err := selectDestination(cfg.Destinations)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
 	if err := minioClient.Init(cfg); err != nil {
@@ -59,74 +211,137 @@ func main() {
 		os.Exit(1)
 	}
 
-	if *onlyListing {
-		list(dest)
+	return dest
+}
 
-		return
+// transferOptions returns the options of the uploads given by the flags.
+func transferOptions() minioClient.TransferOptions {
+	return minioClient.TransferOptions{Retries: *retries, Resumable: *resume}
+}
+
+// selectDestination picks the destination named by "-d", the only one
+// configured or asks for one on a terminal. Without a terminal, there is no
+// default when many destinations are configured.
+func selectDestination(destinations []config.Destination) (config.Destination, error) {
+	if *destName != "" {
+		names := make([]string, 0, len(destinations))
+		for _, d := range destinations {
+			if d.Name == *destName || d.ID == *destName {
+				return d, nil
+			}
+			names = append(names, d.Name)
+		}
+
+		return config.Destination{}, fmt.Errorf("destination %q not found. Options: %s", *destName, strings.Join(names, ", "))
 	}
 
-	if len(flag.Args()) == 0 {
-		usage()
-		os.Exit(1)
+	if len(destinations) == 1 {
+		return destinations[0], nil
+	}
+
+	if !isTerminal(os.Stdin) {
+		names := make([]string, 0, len(destinations))
+		for _, d := range destinations {
+			names = append(names, d.Name)
+		}
+
+		return config.Destination{}, fmt.Errorf("many destinations configured, choose one with -d. Options: %s", strings.Join(names, ", "))
+	}
+
+	destIdx := chooseDestination(destinations)
+	qtd := len(destinations)
+	if qtd < 255 && destIdx >= uint8(qtd) {
+		os.Exit(0)
 	}
 
-	upload(dest)
+	return destinations[destIdx], nil
 }
 
-func upload(dest config.Destination) {
-	if (len(flag.Args())-1)%2 != 0 {
-		fmt.Println(`Provide an even number of arguments: <file1> "<param 1>" "<value 1>" <param 2> "<value 2>"…`)
-		os.Exit(1)
+type listItem struct {
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	LastModified time.Time         `json:"lastModified"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+func list(args []string) {
+	parseFlags(newFlagSet("ls"), args)
+	dest := openDestination()
+
+	if !*jsonOutput {
+		fmt.Println("Listing bucket/prefix content…")
 	}
 
-	f, err := os.Open(flag.Args()[0])
+	list, err := minioClient.List(context.Background(), dest)
 	if err != nil {
-		fmt.Printf("Error opening file %s: %v\n", flag.Args()[0], err)
+		fmt.Println(err)
 		os.Exit(1)
 	}
-	defer func() { _ = f.Close() }()
 
-	info, err := f.Stat()
-	if err != nil {
-		fmt.Printf("Error getting file info for %s: %v\n", flag.Args()[0], err)
-		os.Exit(1)
+	prefixLen := len(dest.Prefix)
+	if prefixLen > 0 {
+		prefixLen++
 	}
-	if info.IsDir() {
-		fmt.Printf("Error: %s is a directory, not a file\n", flag.Args()[0])
-		os.Exit(1)
+
+	items := make([]listItem, 0, len(list))
+	for _, obj := range list {
+		items = append(items, listItem{obj.Key[prefixLen:], obj.Size, obj.LastModified, obj.UserMetadata})
 	}
 
-	filename := filepath.Base(flag.Args()[0])
-	params := make(map[string]string, 0)
-	for i := 1; i < len(flag.Args()); i += 2 {
-		if i+1 >= len(flag.Args()) {
-			fmt.Printf("Error: missing value for parameter %s\n", flag.Args()[i])
-			os.Exit(1)
-		}
-		params[flag.Args()[i]] = flag.Args()[i+1]
+	printList(items)
+}
+
+func printList(items []listItem) {
+	if *jsonOutput {
+		printJSON(items)
+
+		return
 	}
 
-	fmt.Println("Uploading files…")
-	if err := minioClient.Upload(context.Background(), dest, f, filename, info.Size(), params); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	for _, item := range items {
+		fmt.Printf("%s\t%d\n", item.Key, item.Size)
 	}
+}
 
-	fmt.Println("Done!")
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
 
-func list(dest config.Destination) {
-	fmt.Println("Listing bucket/prefix content…")
+func checkMirror(args []string) {
+	parseFlags(newFlagSet("mirrors"), args)
+	dest := openDestination()
 
-	list, err := minioClient.List(context.Background(), dest)
-	if err != nil {
-		fmt.Println(err)
+	if len(dest.Mirrors()) == 0 {
+		fmt.Printf("Destination %q has no mirrors\n", dest.Name)
 		os.Exit(1)
 	}
 
-	for _, obj := range list {
-		fmt.Printf("%s\t%d\n", obj.Key[len(dest.Prefix)+1:], obj.Size)
+	drifted := false
+	for _, m := range dest.Mirrors() {
+		fmt.Printf("Comparing with mirror %q…\n", m.Name)
+
+		drifts, err := minioClient.CheckMirror(context.Background(), dest, m)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		for _, d := range drifts {
+			fmt.Printf("%s\t%s\n", d.Reason, d.Key)
+		}
+		drifted = drifted || len(drifts) > 0
 	}
+
+	if drifted {
+		os.Exit(2)
+	}
+
+	fmt.Println("No differences found")
 }
 
 func isTerminal(f *os.File) bool {
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+const (
+	PROGRESS_BAR_WIDTH     = 30
+	PROGRESS_TTY_INTERVAL  = 200 * time.Millisecond
+	PROGRESS_LINE_INTERVAL = 10 * time.Second
+)
+
+// progress reports the bytes sent by the uploads on the standard error: as a
+// bar redrawn in place when the standard output is a terminal or as periodic
+// lines otherwise (logs of cron jobs, pipes…).
+type progress struct {
+	total int64 // unknown when negative
+	sent  atomic.Int64
+	start time.Time
+	tty   bool
+	done  chan struct{}
+	ended chan struct{}
+}
+
+// startProgress starts reporting the uploads of this process.
+func startProgress(total int64) *progress {
+	p := &progress{
+		total: total,
+		start: time.Now(),
+		tty:   isTerminal(os.Stdout),
+		done:  make(chan struct{}),
+		ended: make(chan struct{}),
+	}
+
+	go p.run()
+
+	return p
+}
+
+func (p *progress) add(n int64) {
+	p.sent.Add(n)
+}
+
+// transfer returns the options of the uploads reported by p.
+func (p *progress) transfer() minioClient.TransferOptions {
+	opts := transferOptions()
+	opts.Progress = p.add
+
+	return opts
+}
+
+func (p *progress) run() {
+	defer close(p.ended)
+
+	interval := PROGRESS_LINE_INTERVAL
+	if p.tty {
+		interval = PROGRESS_TTY_INTERVAL
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			if p.tty {
+				fmt.Fprintf(os.Stderr, "\r%s\n", p.status())
+			}
+
+			return
+		case <-ticker.C:
+			if p.tty {
+				fmt.Fprintf(os.Stderr, "\r%s\033[K", p.status())
+
+				continue
+			}
+			fmt.Fprintln(os.Stderr, p.status())
+		}
+	}
+}
+
+// stop ends the report, leaving the final state of the bar on terminals.
+func (p *progress) stop() {
+	close(p.done)
+	<-p.ended
+}
+
+func (p *progress) status() string {
+	sent := p.sent.Load()
+	if p.total >= 0 {
+		// retried parts are counted again
+		sent = min(sent, p.total)
+	}
+
+	elapsed := time.Since(p.start).Seconds()
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(sent) / elapsed
+	}
+
+	if p.total < 0 {
+		return fmt.Sprintf("%s sent %s/s", formatBytes(sent), formatBytes(int64(rate)))
+	}
+
+	ratio := 1.0
+	if p.total > 0 {
+		ratio = float64(sent) / float64(p.total)
+	}
+
+	eta := "--"
+	if rate > 0 {
+		eta = time.Duration(float64(p.total-sent) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+
+	status := fmt.Sprintf("%3.0f%% %s / %s %s/s ETA %s", ratio*100, formatBytes(sent), formatBytes(p.total), formatBytes(int64(rate)), eta)
+	if !p.tty {
+		return status
+	}
+
+	filled := int(ratio * PROGRESS_BAR_WIDTH)
+
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", PROGRESS_BAR_WIDTH-filled) + "] " + status
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
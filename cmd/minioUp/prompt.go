@@ -0,0 +1,137 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hitalos/minioUp/config"
+)
+
+// loadParamsFile adds the params of a JSON or YAML file to the ones without a
+// value given by "-p".
+func loadParamsFile(filename string) error {
+	b, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return err
+	}
+
+	fileParams := map[string]string{}
+	switch filepath.Ext(filename) {
+	case ".json":
+		err = json.Unmarshal(b, &fileParams)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(b, &fileParams)
+	default:
+		return fmt.Errorf("params file must be .json, .yml or .yaml: %s", filename)
+	}
+	if err != nil {
+		return fmt.Errorf("error decoding params file: %w", err)
+	}
+
+	for k, v := range fileParams {
+		if _, ok := params[k]; !ok {
+			params[k] = v
+		}
+	}
+
+	return nil
+}
+
+// fillParams completes p with the fields of the destination. On a terminal,
+// each missing field is asked showing its description, example and initial
+// value, until a valid value is given. Otherwise, the initial values are used.
+func fillParams(dest config.Destination, p map[string]string, interactive bool) error {
+	for _, name := range slices.Sorted(maps.Keys(dest.Fields)) {
+		if _, ok := p[name]; ok {
+			continue
+		}
+
+		f := dest.Fields[name]
+		if !interactive {
+			if f.Value != "" {
+				p[name] = f.Value
+			}
+
+			continue
+		}
+
+		value, err := promptField(name, f)
+		if err != nil {
+			return err
+		}
+		if value != "" {
+			p[name] = value
+		}
+	}
+
+	return nil
+}
+
+func promptField(name string, f config.Field) (string, error) {
+	label := fmt.Sprintf("%s (%s)", f.Description, name)
+	if f.Example != nil {
+		label += fmt.Sprintf(" e.g. %s", f.Example)
+	}
+	if f.Value != "" {
+		label += fmt.Sprintf(" [%s]", f.Value)
+	}
+	if f.IsRequired {
+		label += " *"
+	}
+
+	initial := f.Value
+	for {
+		line, err := readLine(label + ": ")
+		if err != nil {
+			return "", fmt.Errorf("error reading field %q: %w", name, err)
+		}
+
+		f.Value = cmp.Or(line, initial)
+
+		switch {
+		case f.Value == "" && f.IsRequired:
+			fmt.Println("This field is required")
+		case f.Value == "":
+			return "", nil
+		case !f.Validate():
+			fmt.Printf("Invalid value, it must match %s\n", f.Pattern)
+		default:
+			return f.Value, nil
+		}
+	}
+}
+
+// readLine prints prompt and reads a line of the standard input. Bytes are read
+// one at a time, so nothing typed after the line is held in a buffer, away from
+// the menus reading the terminal.
+func readLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+
+	line := []byte{}
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n > 0 && b[0] != '\n' {
+			line = append(line, b[0])
+
+			continue
+		}
+
+		if n > 0 || (err == io.EOF && len(line) > 0) {
+			return strings.TrimSpace(string(line)), nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+// remoteClient uses the API of a minioUp server, so the S3 credentials stay
+// on the server and its role checks, notifications and logs apply.
+type remoteClient struct {
+	baseURL string
+	token   string
+}
+
+const REMOTE_TIMEOUT = 30 * time.Second
+
+var (
+	errTransient = errors.New("transient error")
+
+	// the timeouts don't count the time sending the body, so large uploads
+	// aren't cut, but a server not answering is
+	httpClient = &http.Client{Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: REMOTE_TIMEOUT}).DialContext,
+		TLSHandshakeTimeout:   REMOTE_TIMEOUT,
+		ResponseHeaderTimeout: 2 * REMOTE_TIMEOUT,
+		IdleConnTimeout:       3 * REMOTE_TIMEOUT,
+	}}
+)
+
+// remote runs a command against the server given by "-remote",
+// authenticated by the API token of $MINIOUP_TOKEN.
+func remote(name string, args []string) {
+	token := os.Getenv("MINIOUP_TOKEN")
+	if token == "" {
+		fmt.Println("Set the API token of the server on MINIOUP_TOKEN")
+		os.Exit(1)
+	}
+	rc := remoteClient{strings.TrimSuffix(*remoteURL, "/"), token}
+	ctx := context.Background()
+
+	switch name {
+	case "ls":
+		parseFlags(newFlagSet("ls"), args)
+		dest := rc.destination(ctx)
+
+		items := []listItem{}
+		if err := rc.do(ctx, http.MethodGet, "/api/"+url.PathEscape(dest.ID)+"/files", nil, "", &items); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		printList(items)
+	case "destinations":
+		flags := newFlagSet("destinations")
+		onlyNames := flags.Bool("names", false, "Print only the names")
+		parseFlags(flags, args)
+
+		printDestinations(rc.destinations(ctx), *onlyNames)
+	case "upload":
+		rc.upload(ctx, args)
+	case "rm":
+		rc.rm(ctx, args)
+	default:
+		fmt.Printf("The command %q is not available with -remote\n", name)
+		os.Exit(1)
+	}
+}
+
+// destinations returns the destinations allowed to the token. Their IDs
+// identify them on the API.
+func (rc remoteClient) destinations(ctx context.Context) []config.Destination {
+	dests := []config.Destination{}
+	if err := rc.do(ctx, http.MethodGet, "/api/destinations", nil, "", &dests); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if len(dests) == 0 {
+		fmt.Println("No destination(s) allowed")
+		os.Exit(1)
+	}
+
+	return dests
+}
+
+func (rc remoteClient) destination(ctx context.Context) config.Destination {
+	dest, err := selectDestination(rc.destinations(ctx))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	return dest
+}
+
+func (rc remoteClient) upload(ctx context.Context, args []string) {
+	flags := newFlagSet("upload")
+	parseFlags(flags, args)
+
+	if flags.Arg(0) == "-" {
+		fmt.Println("Uploading from the standard input is not available with -remote")
+		os.Exit(1)
+	}
+
+	if flags.NArg() == 0 && *manifest == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	dest := rc.destination(ctx)
+	files, fileParams, err := uploadArgs(dest, flags.Args())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		items := make([]previewItem, 0, len(files))
+		for i, file := range files {
+			items = append(items, rc.preview(ctx, dest.ID, file, fileParams[i]))
+		}
+		printPreviews(items)
+
+		return
+	}
+
+	if !*jsonOutput {
+		fmt.Println("Uploading files…")
+	}
+
+	total := int64(0)
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			total += info.Size()
+		}
+	}
+	p := startProgress(total)
+
+	results := make([]minioClient.EntryResult, len(files))
+	sem := make(chan struct{}, max(*concurrency, 1))
+	wg := new(sync.WaitGroup)
+	for i, file := range files {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			results[i] = minioClient.EntryResult{Name: file, Error: rc.uploadFile(ctx, dest.ID, file, fileParams[i], p)}
+		})
+	}
+	wg.Wait()
+	p.stop()
+
+	printUploadResults(dest, results)
+}
+
+// uploadFile sends a file retrying transient errors with exponential backoff.
+func (rc remoteClient) uploadFile(ctx context.Context, destID string, file string, params map[string]string, p *progress) error {
+	for attempt := 0; ; attempt++ {
+		err := rc.sendFile(ctx, destID, file, params, p)
+		if err == nil || attempt >= *retries || !errors.Is(err, errTransient) {
+			return err
+		}
+
+		time.Sleep(time.Second << attempt)
+	}
+}
+
+func (rc remoteClient) sendFile(ctx context.Context, destID string, file string, params map[string]string, p *progress) error {
+	f, err := os.Open(filepath.Clean(file))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		for k, v := range params {
+			if err := mw.WriteField(k, v); err != nil {
+				_ = pw.CloseWithError(err)
+
+				return
+			}
+		}
+
+		part, err := mw.CreateFormFile("file", filepath.Base(file))
+		if err == nil {
+			_, err = io.Copy(part, io.TeeReader(f, minioClient.ProgressFunc(p.add)))
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		_ = pw.CloseWithError(err)
+	}()
+
+	return rc.do(ctx, http.MethodPost, "/api/"+url.PathEscape(destID)+"/files", pr, mw.FormDataContentType(), nil)
+}
+
+func (rc remoteClient) preview(ctx context.Context, destID string, file string, params map[string]string) previewItem {
+	item := previewItem{File: file}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		item.Error = err.Error()
+
+		return item
+	}
+
+	form := url.Values{"filename": {filepath.Base(file)}, "size": {strconv.FormatInt(info.Size(), 10)}}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+
+	path := "/d/" + url.PathEscape(destID) + "/preview"
+	if err := rc.do(ctx, http.MethodPost, path, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded", &item); err != nil {
+		item.Error = err.Error()
+	}
+
+	return item
+}
+
+func (rc remoteClient) rm(ctx context.Context, args []string) {
+	flags := newFlagSet("rm")
+	force := flags.Bool("f", false, "Don't ask for confirmation")
+	parseFlags(flags, args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	keys := flags.Args()
+	dest := rc.destination(ctx)
+
+	if !*force && isTerminal(os.Stdin) && !confirm(fmt.Sprintf("Delete %d file(s) from %q?", len(keys), dest.Name)) {
+		os.Exit(0)
+	}
+
+	failed := false
+	for _, key := range keys {
+		path := "/api/" + url.PathEscape(dest.ID) + "/files/" + url.PathEscape(key)
+		if err := rc.do(ctx, http.MethodDelete, path, nil, "", nil); err != nil {
+			failed = true
+			fmt.Printf("FAIL\t%s\t%v\n", key, err)
+
+			continue
+		}
+		fmt.Printf("OK\t%s\n", key)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// do sends a request to the API decoding the JSON response into v. Error
+// responses are returned with the message of the server.
+func (rc remoteClient) do(ctx context.Context, method, path string, body io.Reader, contentType string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, rc.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+rc.token)
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errTransient, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := struct {
+			Error string `json:"error"`
+		}{}
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(b, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = strings.TrimSpace(string(b))
+		}
+
+		err := fmt.Errorf("%s: %s", resp.Status, apiErr.Error)
+		if resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusInsufficientStorage {
+			err = fmt.Errorf("%w: %w", errTransient, err)
+		}
+
+		return err
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"crypto/md5" // #nosec G501 -- compared with S3 ETags
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+type syncAction struct {
+	key    string
+	path   string
+	reason string
+	size   int64
+	mtime  time.Time
+}
+
+// syncDir mirrors a local directory into the destination, keeping the paths
+// relative to dir under the destination prefix.
+func syncDir(args []string) {
+	flags := newFlagSet("sync")
+	dryRun := flags.Bool("dry-run", false, "Only show what would be done")
+	deleteMissing := flags.Bool("delete", false, "Delete remote files missing on the local directory")
+	parseFlags(flags, args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	dir := flags.Arg(0)
+	dest := openDestination()
+
+	ctx := context.Background()
+	list, err := minioClient.List(ctx, dest)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	remote := make(map[string]minio.ObjectInfo, len(list))
+	for _, obj := range list {
+		remote[minioClient.RelativeKey(dest, obj.Key)] = obj
+	}
+
+	uploads := []syncAction{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		action := syncAction{key: filepath.ToSlash(rel), path: path, size: info.Size(), mtime: info.ModTime()}
+		obj, exists := remote[action.key]
+		delete(remote, action.key)
+
+		switch {
+		case !exists:
+			action.reason = "new"
+		case isChanged(action, obj):
+			action.reason = "changed"
+		default:
+			return nil
+		}
+		uploads = append(uploads, action)
+
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+
+	deletions := []string{}
+	if *deleteMissing {
+		deletions = slices.Sorted(maps.Keys(remote))
+	}
+
+	for _, a := range uploads {
+		fmt.Printf("upload\t%s (%s)\n", a.key, a.reason)
+	}
+	for _, key := range deletions {
+		fmt.Printf("delete\t%s\n", key)
+	}
+
+	if *dryRun {
+		fmt.Printf("%d to upload, %d to delete (dry run)\n", len(uploads), len(deletions))
+
+		return
+	}
+
+	uploadFailures := syncUploads(ctx, dest, uploads)
+	deleteFailures := 0
+	if len(deletions) > 0 {
+		for key, err := range minioClient.DeleteMultiple(ctx, dest, deletions) {
+			deleteFailures++
+			fmt.Printf("FAIL\t%s\t%v\n", key, err)
+		}
+	}
+
+	fmt.Printf("%d uploaded, %d deleted, %d failed\n",
+		len(uploads)-uploadFailures, len(deletions)-deleteFailures, uploadFailures+deleteFailures)
+	if uploadFailures+deleteFailures > 0 {
+		os.Exit(1)
+	}
+}
+
+// isChanged compares the local file with the remote object by size, by the
+// modification time saved on upload and, at last, by checksum.
+func isChanged(local syncAction, obj minio.ObjectInfo) bool {
+	if local.size != obj.Size {
+		return true
+	}
+
+	if mtime := minioClient.MetadataValue(obj.UserMetadata, "mtime"); mtime == local.mtime.UTC().Format(time.RFC3339) {
+		return false
+	}
+
+	// multipart uploads have no MD5 on ETag
+	if strings.Contains(obj.ETag, "-") {
+		return local.mtime.After(obj.LastModified)
+	}
+
+	sum, err := md5sum(local.path)
+	if err != nil {
+		return true
+	}
+
+	return sum != obj.ETag
+}
+
+func md5sum(path string) (string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := md5.New() // #nosec G401
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func syncUploads(ctx context.Context, dest config.Destination, uploads []syncAction) int {
+	mu := new(sync.Mutex)
+	failed := 0
+	sem := make(chan struct{}, max(*concurrency, 1))
+	wg := new(sync.WaitGroup)
+
+	for _, a := range uploads {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			err := syncUpload(ctx, dest, a)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				fmt.Printf("FAIL\t%s\t%v\n", a.key, err)
+
+				return
+			}
+			fmt.Printf("OK\t%s\n", a.key)
+		})
+	}
+	wg.Wait()
+
+	return failed
+}
+
+func syncUpload(ctx context.Context, dest config.Destination, a syncAction) error {
+	f, err := os.Open(filepath.Clean(a.path))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	p := maps.Clone(params)
+	if err := fillParams(dest, p, false); err != nil {
+		return err
+	}
+	p["mtime"] = a.mtime.UTC().Format(time.RFC3339)
+
+	return minioClient.UploadKey(ctx, dest, f, a.key, a.size, p, transferOptions())
+}
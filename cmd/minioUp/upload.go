@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+type (
+	uploadResult struct {
+		File  string `json:"file"`
+		OK    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+	}
+
+	uploadSummary struct {
+		Destination string         `json:"destination"`
+		Uploaded    int            `json:"uploaded"`
+		Failed      int            `json:"failed"`
+		Results     []uploadResult `json:"results"`
+	}
+)
+
+// paramsFlag collects repeated "-p key=value" flags.
+type paramsFlag map[string]string
+
+func (p paramsFlag) String() string {
+	pairs := make([]string, 0, len(p))
+	for k, v := range p {
+		pairs = append(pairs, k+"="+v)
+	}
+	slices.Sort(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (p paramsFlag) Set(v string) error {
+	k, val, ok := strings.Cut(v, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("invalid param %q, use key=value", v)
+	}
+	p[k] = val
+
+	return nil
+}
+
+// upload sends files and globs given as arguments, or the standard input with
+// the argument "-".
+func upload(args []string) {
+	flags := newFlagSet("upload")
+	name := flags.String("name", "", "Name of the file uploaded from the standard input")
+	parseFlags(flags, args)
+
+	if flags.Arg(0) == "-" {
+		// flags after "-" are accepted too
+		_ = flags.Parse(flags.Args()[1:])
+		if *name == "" || flags.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, "Provide the name of the file with -name")
+			flags.Usage()
+			os.Exit(1)
+		}
+		uploadStdin(openDestination(), *name)
+
+		return
+	}
+
+	if flags.NArg() == 0 && *manifest == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	dest := openDestination()
+	files, fileParams, err := uploadArgs(dest, flags.Args())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		previewUploads(dest, files, fileParams)
+
+		return
+	}
+
+	if !*jsonOutput {
+		fmt.Println("Uploading files…")
+	}
+	total := int64(0)
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			total += info.Size()
+		}
+	}
+
+	p := startProgress(total)
+	results := minioClient.UploadMultiple(context.Background(), dest, files, fileParams, *concurrency, p.transfer())
+	p.stop()
+	printUploadResults(dest, results)
+}
+
+// uploadStdin streams the standard input to the destination with the name
+// given by "-name".
+func uploadStdin(dest config.Destination, name string) {
+	if !*jsonOutput {
+		fmt.Println("Uploading from standard input…")
+	}
+	// the standard input is the content, so the fields can't be asked
+	p := maps.Clone(params)
+	if err := fillParams(dest, p, false); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		// the size of the standard input is unknown
+		printPreviews([]previewItem{previewUpload(dest, name, -1, p)})
+
+		return
+	}
+
+	progress := startProgress(-1)
+	err := minioClient.Upload(context.Background(), dest, os.Stdin, name, -1, p, progress.transfer())
+	progress.stop()
+	printUploadResults(dest, []minioClient.EntryResult{{Name: name, Error: err}})
+}
+
+type previewItem struct {
+	File string `json:"file"`
+	minioClient.UploadPreview
+	Error string `json:"error,omitempty"`
+}
+
+// previewUploads shows where each file would be written, without sending it.
+func previewUploads(dest config.Destination, files []string, fileParams []map[string]string) {
+	items := make([]previewItem, 0, len(files))
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			items = append(items, previewItem{File: file, Error: err.Error()})
+
+			continue
+		}
+		items = append(items, previewUpload(dest, file, info.Size(), fileParams[i]))
+	}
+
+	printPreviews(items)
+}
+
+func previewUpload(dest config.Destination, file string, size int64, params map[string]string) previewItem {
+	item := previewItem{File: file}
+
+	preview, err := minioClient.Preview(context.Background(), dest, file, size, params)
+	if err != nil {
+		item.Error = err.Error()
+
+		return item
+	}
+	item.UploadPreview = preview
+
+	return item
+}
+
+func printPreviews(items []previewItem) {
+	failed := 0
+	for _, item := range items {
+		if item.Error != "" {
+			failed++
+		}
+	}
+
+	if *jsonOutput {
+		printJSON(items)
+	} else {
+		for _, item := range items {
+			switch {
+			case item.Error != "":
+				fmt.Printf("FAIL\t%s\t%s\n", item.File, item.Error)
+			case item.Exists:
+				fmt.Printf("REPLACE\t%s\t%s/%s\n", item.File, item.Bucket, item.Key)
+			default:
+				fmt.Printf("NEW\t%s\t%s/%s\n", item.File, item.Bucket, item.Key)
+			}
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func printUploadResults(dest config.Destination, results []minioClient.EntryResult) {
+	summary := uploadSummary{Destination: dest.Name, Results: make([]uploadResult, 0, len(results))}
+	for _, r := range results {
+		res := uploadResult{File: r.Name, OK: r.Error == nil}
+		if r.Error != nil {
+			res.Error = r.Error.Error()
+			summary.Failed++
+		} else {
+			summary.Uploaded++
+		}
+		summary.Results = append(summary.Results, res)
+	}
+
+	if *jsonOutput {
+		printJSON(summary)
+	} else {
+		for _, r := range summary.Results {
+			if !r.OK {
+				fmt.Printf("FAIL\t%s\t%s\n", r.File, r.Error)
+
+				continue
+			}
+			fmt.Printf("OK\t%s\n", r.File)
+		}
+
+		fmt.Printf("%d uploaded, %d failed\n", summary.Uploaded, summary.Failed)
+	}
+
+	if summary.Failed > 0 {
+		os.Exit(1)
+	}
+
+	if !*jsonOutput {
+		fmt.Println("Done!")
+	}
+}
+
+// uploadArgs expands the globs of args and returns the files to upload with
+// their params: the "-p" ones (or asked on a terminal) merged with the ones of
+// the manifest, whose keys are files or globs (the params of a file override
+// the ones of its glob). The legacy form "<file> <param1> <value1> <param2>
+// <value2>…" is still accepted.
+func uploadArgs(dest config.Destination, args []string) ([]string, []map[string]string, error) {
+	common := maps.Clone(params)
+	if isLegacyArgs(args) {
+		for i := 1; i < len(args); i += 2 {
+			common[args[i]] = args[i+1]
+		}
+		args = args[:1]
+	}
+
+	// the fields given by the manifest can't be asked once for all files
+	interactive := *manifest == "" && !*jsonOutput && isTerminal(os.Stdin)
+	if err := fillParams(dest, common, interactive); err != nil {
+		return nil, nil, err
+	}
+
+	manifestParams := map[string]map[string]string{}
+	if *manifest != "" {
+		b, err := os.ReadFile(filepath.Clean(*manifest))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if err := json.Unmarshal(b, &manifestParams); err != nil {
+			return nil, nil, fmt.Errorf("error decoding manifest: %w", err)
+		}
+
+		for _, file := range slices.Sorted(maps.Keys(manifestParams)) {
+			args = append(args, file)
+		}
+	}
+
+	files := []string{}
+	seen := map[string]bool{}
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+		}
+
+		// a missing file is kept to be reported on the summary
+		if len(matches) == 0 {
+			matches = []string{arg}
+		}
+
+		for _, m := range matches {
+			if !seen[m] {
+				seen[m] = true
+				files = append(files, m)
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no files to upload")
+	}
+
+	fileParams := make([]map[string]string, len(files))
+	for i, file := range files {
+		fileParams[i] = maps.Clone(common)
+		for _, pattern := range slices.Sorted(maps.Keys(manifestParams)) {
+			if ok, _ := filepath.Match(pattern, file); ok && pattern != file {
+				maps.Copy(fileParams[i], manifestParams[pattern])
+			}
+		}
+		maps.Copy(fileParams[i], manifestParams[file])
+	}
+
+	return files, fileParams, nil
+}
+
+// isLegacyArgs tells if args are in the form "<file> <param1> <value1>…": an
+// odd number of them, the first an existing file (or a glob matching one) and
+// no param named as an existing file. Otherwise they are all files, and the
+// missing ones are reported as not found.
+func isLegacyArgs(args []string) bool {
+	if len(args) < 3 || len(args)%2 == 0 {
+		return false
+	}
+
+	if matches, _ := filepath.Glob(args[0]); len(matches) == 0 {
+		return false
+	}
+
+	for i := 1; i < len(args); i += 2 {
+		if _, err := os.Stat(args[i]); err == nil {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsLegacyArgs(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, name := range []string{"a.pdf", "b.pdf", "year"} {
+		if err := os.WriteFile(name, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{"one file", []string{"a.pdf"}, false},
+		{"file and one param", []string{"a.pdf", "code", "123"}, true},
+		{"file and two params", []string{"a.pdf", "code", "123", "name", "x"}, true},
+		{"many files", []string{"a.pdf", "b.pdf"}, false},
+		{"three files", []string{"a.pdf", "b.pdf", "a.pdf"}, false},
+		{"any param", []string{"a.pdf", "other", "123"}, true},
+		{"param named as a file", []string{"a.pdf", "year", "2024"}, false},
+		{"missing file", []string{"missing.pdf", "code", "123"}, false},
+		{"glob", []string{"*.pdf", "code", "123"}, true},
+		{"missing value", []string{"a.pdf", "code", "123", "name"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isLegacyArgs(tt.args); got != tt.want {
+				t.Errorf("isLegacyArgs(%q) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
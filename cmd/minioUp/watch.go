@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+const (
+	WATCH_MAX_ATTEMPTS = 5
+	WATCH_RETRY_DELAY  = 30 * time.Second
+)
+
+type (
+	watchOptions struct {
+		pattern *regexp.Regexp
+		moveTo  string
+		remove  bool
+	}
+
+	pendingFile struct {
+		size       int64
+		modTime    time.Time
+		lastChange time.Time
+		attempts   int
+		retryAt    time.Time
+	}
+
+	watchJob struct {
+		path     string
+		attempts int
+	}
+)
+
+// watch uploads every file written into dir once it stops changing, then
+// moves or deletes the local copy. Files already on dir are uploaded too.
+// Uploads failing with transient errors are tried again later, up to
+// WATCH_MAX_ATTEMPTS times; files not matching the pattern, when they change.
+func watch(args []string) {
+	flags := newFlagSet("watch")
+	pattern := flags.String("pattern", "", "Regex with named groups to extract the params from filenames (others are ignored)")
+	moveTo := flags.String("move-to", "", "Directory to move the uploaded files to")
+	remove := flags.Bool("delete", false, "Delete the uploaded files")
+	settle := flags.Duration("settle", 2*time.Second, "Time without changes to consider a file complete")
+	parseFlags(flags, args)
+
+	if flags.NArg() != 1 || (*moveTo == "") == !*remove {
+		fmt.Fprintln(os.Stderr, "Provide the directory to watch and one of -move-to or -delete")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if *settle <= 0 {
+		fmt.Fprintln(os.Stderr, "The -settle duration must be positive")
+		os.Exit(1)
+	}
+	dir := flags.Arg(0)
+	dest := openDestination()
+
+	opts := watchOptions{moveTo: *moveTo, remove: *remove}
+	if *pattern != "" {
+		reg, err := regexp.Compile(*pattern)
+		if err != nil {
+			fmt.Printf("Invalid pattern: %v\n", err)
+			os.Exit(1)
+		}
+		opts.pattern = reg
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer func() { _ = watcher.Close() }()
+
+	if err := watcher.Add(dir); err != nil {
+		fmt.Printf("Error watching %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+
+	pending := map[string]*pendingFile{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	for _, e := range entries {
+		pending[filepath.Join(dir, e.Name())] = &pendingFile{size: -1}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// failed uploads are handed back to the loop through failed, which can't
+	// block the workers while the loop is sending to them
+	failed := struct {
+		sync.Mutex
+		jobs []watchJob
+	}{}
+
+	queue := make(chan watchJob)
+	for range max(*concurrency, 1) {
+		go func() {
+			for job := range queue {
+				if err := processWatched(ctx, dest, job.path, opts); err != nil && minioClient.IsTransient(err) {
+					failed.Lock()
+					failed.jobs = append(failed.jobs, watchJob{job.path, job.attempts + 1})
+					failed.Unlock()
+				}
+			}
+		}()
+	}
+	defer close(queue)
+
+	fmt.Printf("Watching %s…\n", dir)
+	ticker := time.NewTicker(*settle / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-watcher.Errors:
+			fmt.Printf("Watch error: %v\n", err)
+		case ev := <-watcher.Events:
+			if ev.Has(fsnotify.Create) || ev.Has(fsnotify.Write) {
+				if _, ok := pending[ev.Name]; !ok {
+					pending[ev.Name] = &pendingFile{size: -1}
+				}
+			}
+
+			if ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+				delete(pending, ev.Name)
+			}
+		case <-ticker.C:
+			failed.Lock()
+			for _, job := range failed.jobs {
+				if _, ok := pending[job.path]; ok {
+					continue
+				}
+
+				if job.attempts >= WATCH_MAX_ATTEMPTS {
+					fmt.Printf("FAIL\t%s\tgiving up after %d attempts\n", job.path, job.attempts)
+
+					continue
+				}
+				pending[job.path] = &pendingFile{size: -1, attempts: job.attempts, retryAt: time.Now().Add(WATCH_RETRY_DELAY * time.Duration(job.attempts))}
+			}
+			failed.jobs = nil
+			failed.Unlock()
+
+			for path, p := range pending {
+				ready, drop := checkPending(path, p, *settle)
+				if ready || drop {
+					delete(pending, path)
+				}
+
+				if ready {
+					queue <- watchJob{path, p.attempts}
+				}
+			}
+		}
+	}
+}
+
+// checkPending tells if the file kept its size and modification time for the
+// settle duration (and the delay of a retry passed). Missing, hidden and non
+// regular files must be dropped.
+func checkPending(path string, p *pendingFile, settle time.Duration) (ready, drop bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || strings.HasPrefix(filepath.Base(path), ".") {
+		return false, true
+	}
+
+	if time.Now().Before(p.retryAt) {
+		return false, false
+	}
+
+	if info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+		p.size, p.modTime, p.lastChange = info.Size(), info.ModTime(), time.Now()
+
+		return false, false
+	}
+
+	return time.Since(p.lastChange) >= settle, false
+}
+
+// processWatched uploads the file and moves or deletes it, returning the error
+// of a failed upload. Skipped files aren't failures, and neither are uploaded
+// files not moved or deleted, as sending them again wouldn't help.
+func processWatched(ctx context.Context, dest config.Destination, path string, opts watchOptions) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Printf("FAIL\t%s\t%v\n", path, err)
+
+		return err
+	}
+
+	p := maps.Clone(params)
+	if opts.pattern != nil {
+		match := opts.pattern.FindStringSubmatch(filepath.Base(path))
+		if match == nil {
+			fmt.Printf("SKIP\t%s\tfilename doesn't match the pattern\n", path)
+
+			return nil
+		}
+
+		for i, name := range opts.pattern.SubexpNames() {
+			if name != "" {
+				p[name] = match[i]
+			}
+		}
+	}
+
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		fmt.Printf("FAIL\t%s\t%v\n", path, err)
+
+		return err
+	}
+
+	err = minioClient.Upload(ctx, dest, f, path, info.Size(), p, transferOptions())
+	_ = f.Close()
+	if err != nil {
+		fmt.Printf("FAIL\t%s\t%v\n", path, err)
+
+		return err
+	}
+
+	if opts.remove {
+		err = os.Remove(path)
+	} else {
+		err = moveFile(path, filepath.Join(opts.moveTo, filepath.Base(path)))
+	}
+	if err != nil {
+		fmt.Printf("FAIL\t%s\tuploaded but not moved/deleted: %v\n", path, err)
+
+		return nil
+	}
+
+	fmt.Printf("OK\t%s\n", path)
+
+	return nil
+}
+
+// moveFile renames src to dst or, when they are on different filesystems,
+// copies it and removes src.
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(filepath.Clean(dst), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dst)
+
+		return err
+	}
+
+	return os.Remove(src)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hitalos/minioUp/services/check"
+)
+
+// checkConfig prints all the problems of the config file (with "-connect",
+// also of its buckets and SMTP server) or, with "-schema", its JSON Schema
+// ("-fragment-schema" for the included files).
+func checkConfig(args []string) {
+	flags := flag.NewFlagSet("check-config", flag.ExitOnError)
+	flags.StringVar(configFile, "c", *configFile, "Config file")
+	connect := flags.Bool("connect", false, "Check if the buckets exist and the SMTP server is reachable")
+	schema := flags.Bool("schema", false, "Print the JSON Schema of the config file")
+	fragmentSchema := flags.Bool("fragment-schema", false, "Print the JSON Schema of the included files")
+	_ = flags.Parse(args)
+
+	if *schema || *fragmentSchema {
+		if err := check.WriteSchema(os.Stdout, *fragmentSchema); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if !check.ConfigFile(os.Stdout, *configFile, *connect) {
+		os.Exit(1)
+	}
+}
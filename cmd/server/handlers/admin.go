@@ -1,9 +1,9 @@
 package handlers
 
 import (
-	"log/slog"
 	"net/http"
 	"slices"
+	"time"
 
 	"github.com/hitalos/minioUp/cmd/server/templates"
 	"github.com/hitalos/minioUp/config"
@@ -19,9 +19,20 @@ func pageData(r *http.Request) map[string]any {
 	}
 }
 
-func Admin(cfg *config.Config) http.HandlerFunc {
+// ReloadStatus is the result of the last reload of the config.
+type ReloadStatus struct {
+	Time     time.Time
+	Method   string
+	Error    string
+	Watching []string
+}
+
+func Admin(status func() ReloadStatus) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := templates.Exec(w, "admin.html", pageData(r)); err != nil {
+		d := pageData(r)
+		d["Reload"] = status()
+
+		if err := templates.Exec(w, "admin.html", d); err != nil {
 			ErrorHandler("Error executing template", err, w, http.StatusInternalServerError)
 		}
 	}
@@ -40,16 +51,19 @@ func ShowConfig(cfg *config.Config) http.HandlerFunc {
 	}
 }
 
-func ReloadConfig(cfg *config.Config, configFile string) http.HandlerFunc {
+// ReloadFunc replaces the running config by the one on the config file,
+// returning it. method identifies what triggered the reload on the logs.
+type ReloadFunc func(method string) (*config.Config, error)
+
+func ReloadConfig(reload ReloadFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if err := cfg.ReloadDestinations(configFile); err != nil {
+		cfg, err := reload("request")
+		if err != nil {
 			ErrorHandler("Error reloading config", err, w, http.StatusBadRequest)
 
 			return
 		}
 
-		slog.Info("config destinations reloaded", "method", "request")
-
 		ShowConfig(cfg)(w, r)
 	}
 }
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+type (
+	apiDestination struct {
+		ID            string                  `json:"id"`
+		Name          string                  `json:"name"`
+		AllowedTypes  []string                `json:"allowedTypes,omitempty"`
+		Fields        map[string]config.Field `json:"fields,omitempty"`
+		MaxUploadSize int64                   `json:"maxUploadSize"`
+	}
+
+	apiFile struct {
+		Key          string            `json:"key"`
+		Size         int64             `json:"size"`
+		LastModified time.Time         `json:"lastModified"`
+		Metadata     map[string]string `json:"metadata,omitempty"`
+	}
+
+	apiError struct {
+		Error string `json:"error"`
+	}
+)
+
+// APIDestinations lists the destinations allowed to the user. Their IDs are
+// used on the other routes of the API.
+func APIDestinations(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dests := filterDestinationsByRoles(r, cfg)
+		list := make([]apiDestination, 0, len(dests))
+		for _, d := range dests {
+			list = append(list, apiDestination{d.ID, d.Name, d.AllowedTypes, d.Fields, d.MaxUploadSize})
+		}
+
+		writeJSON(w, list, http.StatusOK)
+	}
+}
+
+func APIList(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dest, ok := apiDestinationOf(w, r, cfg)
+		if !ok {
+			return
+		}
+
+		list, err := minioClient.List(r.Context(), dest)
+		if err != nil {
+			apiErrorHandler("Error getting file list", err, w, http.StatusInternalServerError)
+
+			return
+		}
+
+		files := make([]apiFile, 0, len(list))
+		for _, obj := range list {
+			files = append(files, apiFile{minioClient.RelativeKey(dest, obj.Key), obj.Size, obj.LastModified, obj.UserMetadata})
+		}
+
+		writeJSON(w, files, http.StatusOK)
+	}
+}
+
+// APIUpload receives a file as the upload form, responding with the key of
+// the new object.
+func APIUpload(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dest, ok := apiDestinationOf(w, r, cfg)
+		if !ok {
+			return
+		}
+
+		if err := r.ParseMultipartForm(dest.MaxUploadSize); err != nil {
+			apiErrorHandler("Error parsing uploaded file", err, w, http.StatusUnprocessableEntity)
+
+			return
+		}
+		file, fh, err := r.FormFile("file")
+		if err != nil {
+			apiErrorHandler("Error getting uploaded file", err, w, http.StatusBadRequest)
+
+			return
+		}
+		defer func() { _ = file.Close() }()
+
+		params := make(map[string]string, len(dest.Fields))
+		for k := range dest.Fields {
+			params[k] = r.PostFormValue(k)
+		}
+
+		username := r.Header.Get("X-Forwarded-Preferred-Username")
+		if username != "" {
+			params["uploadedBy"] = username
+		}
+
+		preview, err := minioClient.Preview(r.Context(), dest, fh.Filename, fh.Size, params)
+		if err == nil {
+			err = minioClient.Upload(r.Context(), dest, file, fh.Filename, fh.Size, params, minioClient.TransferOptions{})
+		}
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, minioClient.ErrQuotaExceeded):
+				status = http.StatusInsufficientStorage
+			case errors.Is(err, minioClient.ErrUserLimitExceeded):
+				status = http.StatusTooManyRequests
+			case errors.Is(err, minioClient.ErrTooLarge):
+				status = http.StatusRequestEntityTooLarge
+			case errors.Is(err, minioClient.ErrMissingField):
+				status = http.StatusBadRequest
+			}
+			apiErrorHandler("Error uploading file", err, w, status)
+
+			return
+		}
+
+		slog.Info("file uploaded by API", "destination", dest.Name, "key", preview.Key, "user", username)
+		writeJSON(w, preview, http.StatusCreated)
+
+		notify(r.Context(), cfg, dest, fmt.Sprintf("New file uploaded at %q", dest.Bucket), params)
+	}
+}
+
+func APIDelete(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dest, ok := apiDestinationOf(w, r, cfg)
+		if !ok {
+			return
+		}
+
+		filename, _ := url.PathUnescape(r.PathValue("filename"))
+		if !filepath.IsLocal(filename) {
+			apiErrorHandler("Invalid file name", fmt.Errorf("%w: %q", minioClient.ErrInvalidKey, filename), w, http.StatusBadRequest)
+
+			return
+		}
+		if _, err := minioClient.Stat(r.Context(), dest, filename); err != nil {
+			apiErrorHandler("Error getting file info", err, w, http.StatusNotFound)
+
+			return
+		}
+
+		if err := minioClient.Delete(r.Context(), dest, filename); err != nil {
+			apiErrorHandler("Error deleting file", err, w, http.StatusInternalServerError)
+
+			return
+		}
+
+		params := map[string]string{
+			"filename":  filename,
+			"deletedBy": r.Header.Get("X-Forwarded-Preferred-Username"),
+		}
+		slog.Info("file deleted by API", "destination", dest.Name, "key", filename, "user", params["deletedBy"])
+		w.WriteHeader(http.StatusNoContent)
+
+		notify(r.Context(), cfg, dest, fmt.Sprintf("File Deleted at %q", dest.Bucket), params)
+	}
+}
+
+func apiDestinationOf(w http.ResponseWriter, r *http.Request, cfg *config.Config) (config.Destination, bool) {
+	dest, ok := destinationOf(r, cfg)
+	if !ok {
+		writeJSON(w, apiError{"destination not found"}, http.StatusNotFound)
+	}
+
+	return dest, ok
+}
+
+func apiErrorHandler(msg string, err error, w http.ResponseWriter, status int) {
+	slog.Error(msg, "error", err)
+	writeJSON(w, apiError{err.Error()}, status)
+}
+
+func writeJSON(w http.ResponseWriter, v any, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Error encoding JSON", "error", err)
+	}
+}
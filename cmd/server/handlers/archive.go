@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+func processArchive(w http.ResponseWriter, r *http.Request, cfg *config.Config, dest config.Destination, file minioClient.ArchiveFile, filename string, size int64, params map[string]string) {
+	entries, err := minioClient.UploadArchive(r.Context(), dest, file, filename, size, params)
+	if err != nil && len(entries) == 0 {
+		ErrorHandler("Error extracting archive", err, w, http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	results := make([]bulkResult, 0, len(entries)+1)
+	extracted := 0
+	for _, e := range entries {
+		results = append(results, bulkResult(e))
+		if e.Error == nil {
+			extracted++
+		}
+	}
+
+	if err != nil {
+		results = append(results, bulkResult{filename, err})
+	}
+
+	showBulkResults(w, r, dest, results)
+
+	if extracted == 0 {
+		return
+	}
+
+	params["originalFilename"] = filename
+	params["extractedFiles"] = strconv.Itoa(extracted)
+	notify(r.Context(), cfg, dest, fmt.Sprintf("New files uploaded at %q", dest.Bucket), params)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hitalos/minioUp/cmd/server/templates"
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+type bulkResult struct {
+	Name  string
+	Error error
+}
+
+func Bulk(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dest, ok := destinationOf(r, cfg)
+		if !ok {
+			NotFoundHandler(w, r)
+
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			ErrorHandler("Error parsing form", err, w, http.StatusBadRequest)
+
+			return
+		}
+
+		keys := r.PostForm["keys"]
+		if len(keys) == 0 {
+			ErrorHandler("No files selected", nil, w, http.StatusBadRequest)
+
+			return
+		}
+
+		// keys like "../x" would reach objects out of the destination prefix
+		for _, key := range keys {
+			if !filepath.IsLocal(key) {
+				ErrorHandler("Invalid file name", fmt.Errorf("%w: %q", minioClient.ErrInvalidPrefix, key), w, http.StatusBadRequest)
+
+				return
+			}
+		}
+
+		results := make([]bulkResult, 0, len(keys))
+		username := r.Header.Get("X-Forwarded-Preferred-Username")
+
+		switch r.PostFormValue("action") {
+		case "delete":
+			failures := minioClient.DeleteMultiple(r.Context(), dest, keys)
+			deleted := []string{}
+			for _, key := range keys {
+				results = append(results, bulkResult{key, failures[key]})
+				if failures[key] == nil {
+					deleted = append(deleted, key)
+				}
+			}
+
+			if len(deleted) > 0 {
+				notify(r.Context(), cfg, dest, fmt.Sprintf("Files Deleted at %q", dest.Bucket), map[string]string{
+					"filename":  strings.Join(deleted, ", "),
+					"deletedBy": username,
+				})
+			}
+		case "move":
+			dests := filterDestinationsByRoles(r, cfg)
+			idx := slices.IndexFunc(dests, func(d config.Destination) bool {
+				return d.ID == r.PostFormValue("target") && d.ID != dest.ID
+			})
+			if idx < 0 {
+				ErrorHandler("Invalid target destination", nil, w, http.StatusBadRequest)
+
+				return
+			}
+			target := dests[idx]
+
+			for _, key := range keys {
+				results = append(results, bulkResult{key, minioClient.Move(r.Context(), dest, target, key)})
+			}
+
+			notify(r.Context(), cfg, target, fmt.Sprintf("Files moved to %q", target.Bucket), map[string]string{
+				"filename": strings.Join(keys, ", "),
+				"movedBy":  username,
+			})
+		case "download":
+			streamZip(w, r, dest, dest.Name+".zip", keys)
+
+			return
+		default:
+			ErrorHandler("Invalid action", nil, w, http.StatusBadRequest)
+
+			return
+		}
+
+		for _, res := range results {
+			if res.Error != nil {
+				slog.Error("Error on bulk action", "error", res.Error, "action", r.PostFormValue("action"), "file", res.Name)
+				showBulkResults(w, r, dest, results)
+
+				return
+			}
+		}
+
+		w.Header().Set("Location", destinationURL(cfg, dest))
+		w.WriteHeader(http.StatusSeeOther)
+	}
+}
+
+func showBulkResults(w http.ResponseWriter, r *http.Request, dest config.Destination, results []bulkResult) {
+	d := pageData(r)
+	d["Destination"] = dest
+	d["Results"] = results
+
+	status := http.StatusOK
+	for _, res := range results {
+		if res.Error != nil {
+			status = http.StatusMultiStatus
+		}
+	}
+
+	w.WriteHeader(status)
+	if err := templates.Exec(w, "bulk.html", d); err != nil {
+		slog.Error("Error executing template", "error", err)
+	}
+}
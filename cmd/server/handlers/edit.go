@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+
+	"github.com/hitalos/minioUp/cmd/server/templates"
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+func ShowEditForm(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dest, ok := destinationOf(r, cfg)
+		if !ok {
+			NotFoundHandler(w, r)
+
+			return
+		}
+
+		filename, _ := url.PathUnescape(r.PathValue("filename"))
+		if !filepath.IsLocal(filename) {
+			ErrorHandler("Invalid file name", fmt.Errorf("%w: %q", minioClient.ErrInvalidKey, filename), w, http.StatusBadRequest)
+
+			return
+		}
+		info, err := minioClient.Stat(r.Context(), dest, filename)
+		if err != nil {
+			ErrorHandler("Error getting file info", err, w, http.StatusNotFound)
+
+			return
+		}
+
+		values := make(map[string]string, len(dest.Fields))
+		for k, f := range dest.Fields {
+			values[k] = f.Value
+			if v := minioClient.MetadataValue(info.UserMetadata, k); v != "" {
+				values[k] = v
+			}
+		}
+
+		d := pageData(r)
+		d["Destination"] = dest
+		d["Filename"] = filename
+		d["Values"] = values
+
+		if err := templates.Exec(w, "edit.html", d); err != nil {
+			ErrorHandler("Error executing template", err, w, http.StatusInternalServerError)
+		}
+	}
+}
+
+func ProcessEditForm(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dest, ok := destinationOf(r, cfg)
+		if !ok {
+			NotFoundHandler(w, r)
+
+			return
+		}
+
+		filename, _ := url.PathUnescape(r.PathValue("filename"))
+		if !filepath.IsLocal(filename) {
+			ErrorHandler("Invalid file name", fmt.Errorf("%w: %q", minioClient.ErrInvalidKey, filename), w, http.StatusBadRequest)
+
+			return
+		}
+
+		params := make(map[string]string, len(dest.Fields))
+		for k := range dest.Fields {
+			params[k] = r.PostFormValue(k)
+		}
+
+		if username := r.Header.Get("X-Forwarded-Preferred-Username"); username != "" {
+			params["editedBy"] = username
+		}
+
+		newName, err := minioClient.Update(r.Context(), dest, filename, params)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, minioClient.ErrObjectExists) {
+				status = http.StatusConflict
+			}
+			ErrorHandler("Error editing file", err, w, status)
+
+			return
+		}
+
+		w.Header().Set("Location", destinationURL(cfg, dest))
+		w.WriteHeader(http.StatusSeeOther)
+
+		params["filename"] = newName
+		params["previousFilename"] = filename
+		notify(r.Context(), cfg, dest, fmt.Sprintf("File edited at %q", dest.Bucket), params)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+// Export streams a ZIP archive with every file of a destination, or only
+// those under the "prefix" query param.
+func Export(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dest, ok := destinationOf(r, cfg)
+		if !ok {
+			NotFoundHandler(w, r)
+
+			return
+		}
+
+		subPrefix := strings.Trim(r.URL.Query().Get("prefix"), "/")
+		list, err := minioClient.ListPrefix(r.Context(), dest, subPrefix)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, minioClient.ErrInvalidPrefix) {
+				status = http.StatusBadRequest
+			}
+			ErrorHandler("Error getting file list", err, w, status)
+
+			return
+		}
+
+		prefixLen := len(dest.Prefix)
+		if prefixLen > 0 {
+			prefixLen++
+		}
+
+		keys := make([]string, 0, len(list))
+		for _, obj := range list {
+			keys = append(keys, obj.Key[prefixLen:])
+		}
+
+		filename := dest.Name
+		if subPrefix != "" {
+			filename += "-" + strings.ReplaceAll(subPrefix, "/", "-")
+		}
+
+		streamZip(w, r, dest, filename+".zip", keys)
+	}
+}
+
+// streamZip writes the objects straight into a ZIP archive on the response.
+// As the response is already committed when an object fails, failures are
+// listed in an "errors.txt" entry at the end of the archive.
+func streamZip(w http.ResponseWriter, r *http.Request, dest config.Destination, filename string, keys []string) {
+	// large archives must not be cut by the server write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("Error disabling write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	zw := zip.NewWriter(w)
+	names := make(map[string]bool, len(keys))
+	failures := []string{}
+	for _, key := range keys {
+		if err := addToZip(r, zw, dest, key, names); err != nil {
+			slog.Error("Error adding file to zip", "error", err, "file", key)
+			failures = append(failures, key+": "+err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		if f, err := zw.Create("errors.txt"); err == nil {
+			_, _ = f.Write([]byte(strings.Join(failures, "\n") + "\n"))
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		slog.Error("Error closing zip", "error", err)
+	}
+}
+
+func addToZip(r *http.Request, zw *zip.Writer, dest config.Destination, key string, names map[string]bool) error {
+	obj, err := minioClient.Get(r.Context(), dest, key)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = obj.Close() }()
+
+	info, err := obj.Stat()
+	if err != nil {
+		return err
+	}
+
+	originalFilename := minioClient.MetadataValue(info.UserMetadata, "originalFilename")
+	name := zipEntryName(key, originalFilename, names)
+	// entries like "../x" would be extracted out of the target directory
+	if !filepath.IsLocal(name) || strings.Contains(name, "\\") {
+		return fmt.Errorf("invalid entry name %q", name)
+	}
+
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: info.LastModified,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, obj)
+
+	return err
+}
+
+// zipEntryName keeps the directory of key but restores the original filename
+// of the upload, adding a counter when the name was already used.
+func zipEntryName(key, originalFilename string, names map[string]bool) string {
+	base := path.Base(key)
+	if b := path.Base(originalFilename); originalFilename != "" && b != "." && b != ".." && b != "/" {
+		base = b
+	}
+
+	dir, ext := path.Dir(key), path.Ext(base)
+	name := path.Join(dir, base)
+	for i := 2; names[name]; i++ {
+		name = path.Join(dir, fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(base, ext), i, ext))
+	}
+	names[name] = true
+
+	return name
+}
@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"slices"
 	"sort"
 	"strconv"
@@ -60,10 +62,27 @@ func filterDestinationsByRoles(r *http.Request, cfg *config.Config) []config.Des
 	return dests
 }
 
+// destinationOf returns the destination of the "destID" path value among
+// those allowed to the user.
+func destinationOf(r *http.Request, cfg *config.Config) (config.Destination, bool) {
+	for _, d := range filterDestinationsByRoles(r, cfg) {
+		if d.ID == r.PathValue("destID") {
+			return d, true
+		}
+	}
+
+	return config.Destination{}, false
+}
+
+// destinationURL is the page of the upload form and the files of dest.
+func destinationURL(cfg *config.Config, dest config.Destination) string {
+	return fmt.Sprintf("%s/d/%s/", cfg.URLPrefix, url.PathEscape(dest.ID))
+}
+
 func Index(cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		dests := filterDestinationsByRoles(r, cfg)
-		if len(dests) > 1 {
+		if len(dests) != 1 {
 			d := pageData(r)
 			d["Destinations"] = dests
 
@@ -73,27 +92,55 @@ func Index(cfg *config.Config) http.HandlerFunc {
 
 			return
 		}
-		r.PostForm = url.Values{"destination": []string{"0"}}
+		r.SetPathValue("destID", dests[0].ID)
 		ShowUploadForm(cfg)(w, r)
 	}
 }
 
-func ShowUploadForm(cfg *config.Config) http.HandlerFunc {
+// RedirectToForm sends the choice of the index page, by ID, and the older
+// URLs, by index, to the page of the destination.
+func RedirectToForm(cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		destIdx, err := strconv.Atoi(r.FormValue("destination"))
-		if err != nil {
-			ErrorHandler("Invalid destination", err, w, http.StatusBadRequest)
+		dests := filterDestinationsByRoles(r, cfg)
+		value := r.FormValue("destination")
+
+		for _, d := range dests {
+			if d.ID == value {
+				http.Redirect(w, r, destinationURL(cfg, d), http.StatusSeeOther)
+
+				return
+			}
+		}
+
+		destIdx, err := strconv.Atoi(value)
+		if err != nil || destIdx < 0 || destIdx >= len(dests) {
+			NotFoundHandler(w, r)
 
 			return
 		}
 
-		dest := filterDestinationsByRoles(r, cfg)[destIdx]
+		http.Redirect(w, r, destinationURL(cfg, dests[destIdx]), http.StatusSeeOther)
+	}
+}
+
+func ShowUploadForm(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dest, ok := destinationOf(r, cfg)
+		if !ok {
+			NotFoundHandler(w, r)
+
+			return
+		}
 
 		d := pageData(r)
 		d["Endpoint"] = cfg.Endpoint
 		d["Secure"] = cfg.Secure
+		if dest.Server != nil {
+			d["Endpoint"] = dest.Server.Endpoint
+			d["Secure"] = dest.Server.Secure
+		}
 		d["Destination"] = dest
-		d["DestinationIdx"] = destIdx
+		d["Destinations"] = filterDestinationsByRoles(r, cfg)
 
 		minioList, err := minioClient.List(r.Context(), dest)
 		if err != nil {
@@ -108,7 +155,10 @@ func ShowUploadForm(cfg *config.Config) http.HandlerFunc {
 		}
 
 		list := make(fileInfoList, 0)
+		usage := minioClient.Usage{}
 		for _, obj := range minioList {
+			usage.Bytes += obj.Size
+			usage.Objects++
 			list = append(list, fileInfo{
 				obj.Key[prefixLen:],
 				obj.Size,
@@ -116,6 +166,12 @@ func ShowUploadForm(cfg *config.Config) http.HandlerFunc {
 				map[string]string(obj.UserMetadata)})
 		}
 
+		d["Usage"] = usage
+
+		if username := r.Header.Get("X-Forwarded-Preferred-Username"); dest.UserLimits != nil && username != "" {
+			d["UserUsage"] = minioClient.UserUsageOf(minioList, username, time.Now())
+		}
+
 		sort.Sort(list)
 		d["List"] = list[0:min(dest.MaxResultLength, len(list))]
 
@@ -127,13 +183,12 @@ func ShowUploadForm(cfg *config.Config) http.HandlerFunc {
 
 func ProcessUploadForm(cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		destIdx, err := strconv.Atoi(r.PostFormValue("destination"))
-		if err != nil {
-			ErrorHandler("Error parsing form", err, w, http.StatusBadRequest)
+		dest, ok := destinationOf(r, cfg)
+		if !ok {
+			NotFoundHandler(w, r)
 
 			return
 		}
-		dest := filterDestinationsByRoles(r, cfg)[destIdx]
 
 		if err := r.ParseMultipartForm(dest.MaxUploadSize); err != nil {
 			ErrorHandler("Error parsing uploaded file", err, w, http.StatusUnprocessableEntity)
@@ -159,71 +214,67 @@ func ProcessUploadForm(cfg *config.Config) http.HandlerFunc {
 			params["uploadedBy"] = username
 		}
 
-		if err := minioClient.Upload(r.Context(), dest, file, fh.Filename, fh.Size, params); err != nil {
-			ErrorHandler("Error uploading file", err, w, http.StatusInternalServerError)
+		if dest.ExtractArchives && minioClient.IsArchive(fh.Filename) {
+			processArchive(w, r, cfg, dest, file, fh.Filename, fh.Size, params)
+			_ = file.Close()
 
 			return
 		}
-		_ = file.Close()
-
-		w.Header().Set("Location", fmt.Sprintf("%s/form?destination=%d", cfg.URLPrefix, destIdx))
-		w.WriteHeader(http.StatusSeeOther)
 
-		if dest.WebHook != nil {
-			if err := hitWebHook(r.Context(), dest); err != nil {
-				slog.Error("Error sending webhook", "error", err, "webhook", dest.WebHook)
+		if err := minioClient.Upload(r.Context(), dest, file, fh.Filename, fh.Size, params, minioClient.TransferOptions{}); err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, minioClient.ErrQuotaExceeded):
+				status = http.StatusInsufficientStorage
+			case errors.Is(err, minioClient.ErrUserLimitExceeded):
+				status = http.StatusTooManyRequests
+			case errors.Is(err, minioClient.ErrTooLarge):
+				status = http.StatusRequestEntityTooLarge
+			case errors.Is(err, minioClient.ErrMissingField):
+				status = http.StatusBadRequest
 			}
-		}
+			ErrorHandler("Error uploading file", err, w, status)
 
-		if len(dest.NotifyEmails) != 0 {
-			for _, email := range dest.NotifyEmails {
-				subject := fmt.Sprintf("New file uploaded at %q", dest.Bucket)
-				if err := smtpClient.SendMail(r.Context(), email, subject, *dest.NotifyTemplate, params, *cfg.SMTPconfig); err != nil {
-					slog.Error("Error sending notification email", "error", err, "email", email)
-				}
-			}
+			return
 		}
+		_ = file.Close()
+
+		w.Header().Set("Location", destinationURL(cfg, dest))
+		w.WriteHeader(http.StatusSeeOther)
+
+		notify(r.Context(), cfg, dest, fmt.Sprintf("New file uploaded at %q", dest.Bucket), params)
 	}
 }
 
 func Delete(cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		destIdx, err := strconv.Atoi(r.PathValue("destIdx"))
-		if err != nil {
-			ErrorHandler("Invalid destination", err, w, http.StatusBadRequest)
+		dest, ok := destinationOf(r, cfg)
+		if !ok {
+			NotFoundHandler(w, r)
 
 			return
 		}
-		dest := filterDestinationsByRoles(r, cfg)[destIdx]
 
 		filename, _ := url.PathUnescape(r.PathValue("filename"))
+		if !filepath.IsLocal(filename) {
+			ErrorHandler("Invalid file name", fmt.Errorf("%w: %q", minioClient.ErrInvalidKey, filename), w, http.StatusBadRequest)
+
+			return
+		}
 		if err := minioClient.Delete(r.Context(), dest, filename); err != nil {
 			ErrorHandler("Error deleting file", err, w, http.StatusInternalServerError)
 
 			return
 		}
 
-		w.Header().Set("Location", fmt.Sprintf("%s/form?destination=%d", cfg.URLPrefix, destIdx))
+		w.Header().Set("Location", destinationURL(cfg, dest))
 		w.WriteHeader(http.StatusSeeOther)
 
-		if dest.WebHook != nil {
-			if err := hitWebHook(r.Context(), dest); err != nil {
-				slog.Error("Error sending webhook", "error", err, "webhook", dest.WebHook)
-			}
-		}
-
-		if len(dest.NotifyEmails) != 0 {
-			for _, email := range dest.NotifyEmails {
-				subject := fmt.Sprintf("File Deleted at %q", dest.Bucket)
-				params := map[string]string{
-					"filename":  filename,
-					"deletedBy": r.Header.Get("X-Forwarded-Preferred-Username"),
-				}
-				if err := smtpClient.SendMail(r.Context(), email, subject, *dest.NotifyTemplate, params, *cfg.SMTPconfig); err != nil {
-					slog.Error("Error sending notification email", "error", err, "email", email)
-				}
-			}
+		params := map[string]string{
+			"filename":  filename,
+			"deletedBy": r.Header.Get("X-Forwarded-Preferred-Username"),
 		}
+		notify(r.Context(), cfg, dest, fmt.Sprintf("File Deleted at %q", dest.Bucket), params)
 	}
 }
 
@@ -243,6 +294,24 @@ func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func notify(ctx context.Context, cfg *config.Config, dest config.Destination, subject string, params map[string]string) {
+	if dest.WebHook != nil {
+		if err := hitWebHook(ctx, dest); err != nil {
+			slog.Error("Error sending webhook", "error", err, "webhook", dest.WebHook)
+		}
+	}
+
+	if len(dest.NotifyEmails) == 0 || cfg.SMTPconfig == nil {
+		return
+	}
+
+	for _, email := range dest.NotifyEmails {
+		if err := smtpClient.SendMail(ctx, email, subject, *dest.NotifyTemplate, params, *cfg.SMTPconfig); err != nil {
+			slog.Error("Error sending notification email", "error", err, "email", email)
+		}
+	}
+}
+
 func hitWebHook(ctx context.Context, dest config.Destination) error {
 	method := http.MethodPost
 	if dest.WebHook.Method != "" {
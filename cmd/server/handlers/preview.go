@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+type previewResult struct {
+	minioClient.UploadPreview
+	Error string `json:"error,omitempty"`
+}
+
+// Preview validates an upload described by the form values "filename", "size"
+// and the fields of the destination, responding with the bucket and key that
+// would be written. No file is sent.
+func Preview(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dest, ok := destinationOf(r, cfg)
+		if !ok {
+			writeJSON(w, previewResult{Error: "destination not found"}, http.StatusNotFound)
+
+			return
+		}
+
+		filename := r.PostFormValue("filename")
+		size, err := strconv.ParseInt(r.PostFormValue("size"), 10, 64)
+		if filename == "" || err != nil {
+			writeJSON(w, previewResult{Error: "filename and size are required"}, http.StatusBadRequest)
+
+			return
+		}
+
+		params := make(map[string]string, len(dest.Fields))
+		for k := range dest.Fields {
+			params[k] = r.PostFormValue(k)
+		}
+
+		if username := r.Header.Get("X-Forwarded-Preferred-Username"); username != "" {
+			params["uploadedBy"] = username
+		}
+
+		preview, err := minioClient.Preview(r.Context(), dest, filename, size, params)
+		if err != nil {
+			writeJSON(w, previewResult{Error: err.Error()}, http.StatusUnprocessableEntity)
+
+			return
+		}
+
+		writeJSON(w, previewResult{UploadPreview: preview}, http.StatusOK)
+	}
+}
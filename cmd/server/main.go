@@ -20,15 +20,16 @@ import (
 	"github.com/hitalos/minioUp/cmd/server/handlers"
 	"github.com/hitalos/minioUp/cmd/server/i18n"
 	"github.com/hitalos/minioUp/cmd/server/middlewares"
-	"github.com/hitalos/minioUp/cmd/server/middlewares/auth"
 	"github.com/hitalos/minioUp/cmd/server/public"
-	"github.com/hitalos/minioUp/cmd/server/templates"
 	"github.com/hitalos/minioUp/config"
 	"github.com/hitalos/minioUp/services/minioClient"
 )
 
+const MIRROR_WORKERS = 4
+
 var (
 	configFile = flag.String("c", "config.yml", "Config file")
+	watch      = flag.Bool("watch", false, "Reload the config when it or users.yml change")
 	level      = new(slog.LevelVar)
 	log        = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: level,
@@ -39,6 +40,12 @@ func main() {
 	setLogger()
 	flag.Parse()
 
+	if flag.Arg(0) == "check-config" {
+		checkConfig(flag.Args()[1:])
+
+		return
+	}
+
 	cfg := &config.Config{}
 	if err := cfg.Parse(*configFile); err != nil {
 		if os.IsNotExist(err) {
@@ -51,19 +58,18 @@ func main() {
 	}
 
 	i18n.LoadTranslations()
-	templates.SetURLPrefix(cfg.URLPrefix)
 
-	if err := minioClient.Init(*cfg); err != nil {
-		slog.Error("error on initialize minio client", "error", err)
+	a := &app{configFile: *configFile}
+	if err := a.load(cfg); err != nil {
+		slog.Error("error on initialize server", "error", err)
 		os.Exit(1)
 	}
-
-	r := chi.NewMux()
-	setRoutes(r, cfg)
+	a.setStatus("start", nil)
+	minioClient.StartMirroring(MIRROR_WORKERS)
 
 	s := &http.Server{
 		Addr:         cfg.Port,
-		Handler:      r,
+		Handler:      a,
 		IdleTimeout:  time.Second * 30,
 		ReadTimeout:  time.Second * 30,
 		WriteTimeout: time.Second * 30,
@@ -71,7 +77,11 @@ func main() {
 
 	reloadCh := make(chan os.Signal, 1)
 	signal.Notify(reloadCh, syscall.SIGHUP)
-	go reloadConfig(reloadCh, cfg, *configFile)
+	go a.reloadOnSignal(reloadCh)
+
+	if *watch {
+		go a.watchConfig()
+	}
 
 	go listen(s)
 
@@ -81,6 +91,7 @@ func main() {
 
 	close(reloadCh)
 	shutdown(s)
+	minioClient.StopMirroring()
 }
 
 func setLogger() {
@@ -94,25 +105,41 @@ func setLogger() {
 	slog.SetDefault(log)
 }
 
-func setRoutes(r *chi.Mux, cfg *config.Config) {
+func setRoutes(r *chi.Mux, cfg *config.Config, authenticate func(http.Handler) http.Handler, reload handlers.ReloadFunc, status func() handlers.ReloadStatus) {
 	r.Route(cfg.URLPrefix+"/", func(r chi.Router) {
 		setDefaultMiddlewares(r, cfg)
 
 		r.Route("/", func(r chi.Router) {
-			r.Use(auth.NewAuthenticator(*cfg))
+			r.Use(authenticate)
 
 			r.Get("/", handlers.Index(cfg))
-			r.Post("/form", handlers.ShowUploadForm(cfg))
-			r.Get("/form", handlers.ShowUploadForm(cfg))
-			r.Post("/upload", handlers.ProcessUploadForm(cfg))
-			r.Post("/delete/{destIdx}/{filename}", handlers.Delete(cfg))
+			r.Post("/form", handlers.RedirectToForm(cfg))
+			r.Get("/form", handlers.RedirectToForm(cfg))
+
+			r.Route("/d/{destID}", func(r chi.Router) {
+				r.Get("/", handlers.ShowUploadForm(cfg))
+				r.Post("/upload", handlers.ProcessUploadForm(cfg))
+				r.Post("/preview", handlers.Preview(cfg))
+				r.Post("/delete/{filename}", handlers.Delete(cfg))
+				r.Get("/edit/{filename}", handlers.ShowEditForm(cfg))
+				r.Post("/edit/{filename}", handlers.ProcessEditForm(cfg))
+				r.Post("/bulk", handlers.Bulk(cfg))
+				r.Get("/export", handlers.Export(cfg))
+			})
+
+			r.Route("/api", func(r chi.Router) {
+				r.Get("/destinations", handlers.APIDestinations(cfg))
+				r.Get("/{destID}/files", handlers.APIList(cfg))
+				r.Post("/{destID}/files", handlers.APIUpload(cfg))
+				r.Delete("/{destID}/files/{filename}", handlers.APIDelete(cfg))
+			})
 
 			r.Route("/admin", func(r chi.Router) {
 				r.Use(middlewares.HasRole("admin"))
 
-				r.Get("/", handlers.Admin(cfg))
+				r.Get("/", handlers.Admin(status))
 				r.Get("/config", handlers.ShowConfig(cfg))
-				r.Get("/config/reload", handlers.ReloadConfig(cfg, *configFile))
+				r.Get("/config/reload", handlers.ReloadConfig(reload))
 			})
 		})
 
@@ -150,18 +177,3 @@ func shutdown(server *http.Server) {
 
 	slog.Info("server shutdowned")
 }
-
-func reloadConfig(reloadCh chan os.Signal, cfg *config.Config, configFile string) {
-	for range reloadCh {
-		if err := cfg.ReloadDestinations(configFile); err != nil {
-			slog.Error("error reloading config", "error", err)
-			time.Sleep(10 * time.Second)
-
-			continue
-		}
-
-		slog.Info("config destinations reloaded", "method", "signal")
-
-		time.Sleep(10 * time.Second)
-	}
-}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/hitalos/minioUp/config"
+)
+
+// withAPITokens authenticates the requests with an "Authorization: Bearer"
+// header by the API tokens of the config, setting the same headers of the
+// other drivers. Requests without a known token are handled by authenticate,
+// as reverse proxies may forward bearer tokens of their own.
+func withAPITokens(tokens []config.APIToken, authenticate func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := authenticate(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || len(tokens) == 0 {
+				authenticated.ServeHTTP(w, r)
+
+				return
+			}
+
+			t, ok := findToken(tokens, token)
+			if !ok {
+				slog.Debug("unknown API token, trying the auth driver", "ip", r.RemoteAddr)
+				authenticated.ServeHTTP(w, r)
+
+				return
+			}
+
+			r.Header.Del("X-Roles")
+			for _, role := range t.Roles {
+				r.Header.Add("X-Roles", role)
+			}
+			r.Header.Set("X-Forwarded-Preferred-Username", t.Name)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func findToken(tokens []config.APIToken, token string) (config.APIToken, bool) {
+	sum := sha256.Sum256([]byte(token))
+	for _, t := range tokens {
+		hash, err := hex.DecodeString(t.TokenHash)
+		if err == nil && subtle.ConstantTimeCompare(hash, sum[:]) == 1 {
+			return t, true
+		}
+	}
+
+	return config.APIToken{}, false
+}
@@ -10,11 +10,11 @@ import (
 
 type (
 	Authenticator interface {
-		New(params map[string]string) func(http.Handler) http.Handler
+		New(params map[string]string) (func(http.Handler) http.Handler, error)
 	}
 )
 
-func NewAuthenticator(cfg config.Config) func(http.Handler) http.Handler {
+func NewAuthenticator(cfg config.Config) (func(http.Handler) http.Handler, error) {
 	var authenticator Authenticator
 
 	switch cfg.Auth.Driver {
@@ -23,10 +23,15 @@ func NewAuthenticator(cfg config.Config) func(http.Handler) http.Handler {
 	case "reverseProxy":
 		authenticator = reverseProxy.ReverseProxyAuthenticator{}
 	default:
-		return func(next http.Handler) http.Handler {
+		return withAPITokens(cfg.APITokens, func(next http.Handler) http.Handler {
 			return next
-		}
+		}), nil
 	}
 
-	return authenticator.New(cfg.Auth.Params)
+	authenticate, err := authenticator.New(cfg.Auth.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	return withAPITokens(cfg.APITokens, authenticate), nil
 }
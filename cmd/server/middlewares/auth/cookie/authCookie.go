@@ -1,6 +1,7 @@
 package cookie
 
 import (
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -28,7 +29,7 @@ type (
 	}
 )
 
-func (cAuth CookieAuthenticator) New(params map[string]string) func(http.Handler) http.Handler {
+func (cAuth CookieAuthenticator) New(params map[string]string) (func(http.Handler) http.Handler, error) {
 	cAuth.store = sessions.NewCookieStore([]byte(params["secret"]))
 	cAuth.store.Options = &sessions.Options{
 		HttpOnly: true,
@@ -57,13 +58,12 @@ func (cAuth CookieAuthenticator) New(params map[string]string) func(http.Handler
 
 	f, err := os.Open("users.yml")
 	if err != nil {
-		slog.Error("Error opening users.yml", "error", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("error opening users.yml: %w", err)
 	}
+	defer func() { _ = f.Close() }()
 
 	if err := yaml.NewDecoder(f).Decode(&cAuth.users); err != nil {
-		slog.Error("Error decoding users.yml", "error", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("error decoding users.yml: %w", err)
 	}
 
 	if urlPrefixParam, ok := params["urlPrefix"]; ok && urlPrefixParam != "" {
@@ -117,7 +117,7 @@ func (cAuth CookieAuthenticator) New(params map[string]string) func(http.Handler
 			w.Header().Set("Location", cAuth.urlPrefix+"/auth/login")
 			w.WriteHeader(http.StatusSeeOther)
 		})
-	}
+	}, nil
 }
 
 func (cAuth CookieAuthenticator) showLogin(w http.ResponseWriter, _ *http.Request) {
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/hitalos/minioUp/cmd/server/templates"
@@ -17,12 +16,11 @@ type (
 	}
 )
 
-func (rpAuth ReverseProxyAuthenticator) New(params map[string]string) func(http.Handler) http.Handler {
+func (rpAuth ReverseProxyAuthenticator) New(params map[string]string) (func(http.Handler) http.Handler, error) {
 	rpAuth.header = "X-Forwarded-Groups"
 	clientID, ok := params["clientID"]
 	if !ok || clientID == "" {
-		slog.Error("Missing clientID param of oauth2proxy driver")
-		os.Exit(1)
+		return nil, errors.New("missing clientID param of reverseProxy driver")
 	}
 	rpAuth.clientID = clientID
 
@@ -64,5 +62,5 @@ func (rpAuth ReverseProxyAuthenticator) New(params map[string]string) func(http.
 
 			next.ServeHTTP(w, r)
 		})
-	}
+	}, nil
 }
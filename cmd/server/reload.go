@@ -0,0 +1,258 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/hitalos/minioUp/cmd/server/handlers"
+	"github.com/hitalos/minioUp/cmd/server/middlewares/auth"
+	"github.com/hitalos/minioUp/cmd/server/templates"
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+)
+
+const (
+	// USERS_FILE is read by the cookie authenticator.
+	USERS_FILE      = "users.yml"
+	RELOAD_DEBOUNCE = time.Second
+	// CONFIGMAP_DATA is the symlink swapped by Kubernetes to update the files
+	// of a ConfigMap at once.
+	CONFIGMAP_DATA = "..data"
+)
+
+// app serves the requests with the routes built from the current config. A
+// reload builds everything again (S3 clients, authenticator, middlewares and
+// handlers) and swaps it at once: requests in flight finish with the previous
+// config and its servers.
+type app struct {
+	configFile string
+	cfg        atomic.Pointer[config.Config]
+	handler    atomic.Pointer[chi.Mux]
+	reloadMu   sync.Mutex
+	status     atomic.Pointer[handlers.ReloadStatus]
+	watching   atomic.Pointer[[]string]
+}
+
+func (a *app) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.handler.Load().ServeHTTP(w, r)
+}
+
+// load builds the routes of cfg and, if nothing fails, puts them in use.
+func (a *app) load(cfg *config.Config) error {
+	authenticate, err := auth.NewAuthenticator(*cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := minioClient.Init(*cfg); err != nil {
+		return err
+	}
+
+	r := chi.NewMux()
+	setRoutes(r, cfg, authenticate, a.reload, a.reloadStatus)
+
+	// pages rendered from now on link to the routes about to be served
+	templates.SetURLPrefix(cfg.URLPrefix)
+	a.cfg.Store(cfg)
+	a.handler.Store(r)
+
+	return nil
+}
+
+// reload parses the config file again and replaces the running config only
+// if the new one is valid.
+func (a *app) reload(method string) (*config.Config, error) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	cfg := &config.Config{}
+	if err := cfg.Parse(a.configFile); err != nil {
+		slog.Error("error reloading config", "error", err, "method", method)
+		a.setStatus(method, err)
+
+		return nil, err
+	}
+
+	if old := a.cfg.Load(); cfg.Port != old.Port {
+		slog.Warn("changing the port requires a restart", "port", old.Port)
+	}
+
+	if err := a.load(cfg); err != nil {
+		slog.Error("error reloading config", "error", err, "method", method)
+		a.setStatus(method, err)
+
+		return nil, err
+	}
+
+	slog.Info("config reloaded", "method", method)
+	a.setStatus(method, nil)
+
+	return cfg, nil
+}
+
+func (a *app) reloadOnSignal(reloadCh chan os.Signal) {
+	for range reloadCh {
+		_, _ = a.reload("signal")
+	}
+}
+
+// watchConfig reloads the config when the config file, its included files or
+// users.yml change. Their directories are watched, so files replaced by
+// editors or by the symlink swaps of Kubernetes ConfigMaps are noticed too.
+// Events are debounced and the files compared with their last contents
+// before reloading.
+func (a *app) watchConfig() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Error("error watching config", "error", err)
+
+		return
+	}
+	defer func() { _ = watcher.Close() }()
+
+	files, err := a.watchFiles(watcher)
+	if err != nil {
+		slog.Error("error watching config", "error", err)
+	}
+	slog.Info("watching config files", "files", files)
+
+	sums := fileSums(files)
+	debounce := time.NewTimer(RELOAD_DEBOUNCE)
+	debounce.Stop()
+
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if a.isWatched(ev.Name) {
+				debounce.Reset(RELOAD_DEBOUNCE)
+			}
+		case <-debounce.C:
+			// included files may have been added or removed
+			if files, err = a.watchFiles(watcher); err != nil {
+				slog.Error("error watching config", "error", err)
+			}
+
+			newSums := fileSums(files)
+			if slices.Equal(sums, newSums) {
+				continue
+			}
+			sums = newSums
+
+			_, _ = a.reload("watch")
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Error("error watching config", "error", err)
+		}
+	}
+}
+
+// watchFiles watches the directories of the config files (and of the include
+// patterns, even without matches yet), returning the files.
+func (a *app) watchFiles(watcher *fsnotify.Watcher) ([]string, error) {
+	cfg := a.cfg.Load()
+	files := append(cfg.Files(), USERS_FILE)
+	a.watching.Store(&files)
+
+	dirs := []string{}
+	for _, f := range files {
+		dirs = append(dirs, filepath.Dir(f))
+	}
+	for _, pattern := range cfg.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(a.configFile), pattern)
+		}
+		dirs = append(dirs, filepath.Dir(pattern))
+	}
+
+	slices.Sort(dirs)
+	for _, dir := range slices.Compact(dirs) {
+		if slices.Contains(watcher.WatchList(), dir) {
+			continue
+		}
+
+		if err := watcher.Add(dir); err != nil {
+			return files, fmt.Errorf("error watching %s: %w", dir, err)
+		}
+	}
+
+	return files, nil
+}
+
+// isWatched tells if an event on name concerns the config: a watched file, a
+// file matching an include pattern or the data of a ConfigMap. Other files of
+// the directories (as of ".", watched for users.yml) are ignored.
+func (a *app) isWatched(name string) bool {
+	name = filepath.Clean(name)
+	if filepath.Base(name) == CONFIGMAP_DATA {
+		return true
+	}
+
+	if files := a.watching.Load(); files != nil && slices.ContainsFunc(*files, func(f string) bool {
+		return filepath.Clean(f) == name
+	}) {
+		return true
+	}
+
+	for _, pattern := range a.cfg.Load().Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(a.configFile), pattern)
+		}
+
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fileSums returns the SHA-256 of the files (empty for missing ones).
+func fileSums(files []string) []string {
+	sums := make([]string, len(files))
+	for i, f := range files {
+		if b, err := os.ReadFile(filepath.Clean(f)); err == nil {
+			sums[i] = fmt.Sprintf("%x", sha256.Sum256(b))
+		}
+	}
+
+	return sums
+}
+
+func (a *app) setStatus(method string, err error) {
+	status := handlers.ReloadStatus{Time: time.Now(), Method: method}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	a.status.Store(&status)
+}
+
+func (a *app) reloadStatus() handlers.ReloadStatus {
+	status := handlers.ReloadStatus{}
+	if s := a.status.Load(); s != nil {
+		status = *s
+	}
+
+	if files := a.watching.Load(); files != nil {
+		status.Watching = *files
+	}
+
+	return status
+}
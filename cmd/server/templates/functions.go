@@ -3,19 +3,23 @@ package templates
 import (
 	"fmt"
 	"html/template"
+	"net/url"
 	"strings"
+	"sync/atomic"
 
 	"github.com/hitalos/minioUp/cmd/server/i18n"
 )
 
 var (
 	funcs = template.FuncMap{
-		"humanize":  HumanizeBytes,
-		"i18n":      i18n.Translate,
-		"urlPrefix": getURLPrefix,
+		"humanize":   HumanizeBytes,
+		"i18n":       i18n.Translate,
+		"urlPrefix":  getURLPrefix,
+		"pathEscape": url.PathEscape,
 	}
 
-	urlPrefix string
+	// urlPrefix is replaced on reloads while pages are rendered
+	urlPrefix atomic.Value
 )
 
 func HumanizeBytes(b int64) string {
@@ -35,9 +39,11 @@ func HumanizeBytes(b int64) string {
 }
 
 func getURLPrefix() string {
-	return urlPrefix
+	prefix, _ := urlPrefix.Load().(string)
+
+	return prefix
 }
 
 func SetURLPrefix(prefix string) {
-	urlPrefix = strings.TrimSuffix(prefix, "/")
+	urlPrefix.Store(strings.TrimSuffix(prefix, "/"))
 }
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"strings"
+)
+
+// Check parses and validates the config file as Parse, but returns all the
+// problems found instead of an error, and renders the templates of every
+// destination with sample values. The config is returned to allow the checks
+// that need a connection (buckets, SMTP server).
+func Check(configFile string) (*Config, []error) {
+	c := &Config{}
+	if err := c.load(configFile); err != nil {
+		return nil, []error{err}
+	}
+
+	errs := c.validate()
+
+	return c, append(errs, c.checkTemplates()...)
+}
+
+// checkTemplates renders the model and the notification template of the
+// destinations with the values of their fields (or examples) and the params
+// added on uploads.
+func (c *Config) checkTemplates() []error {
+	errs := []error{}
+	for _, d := range c.Destinations {
+		params := sampleParams(d)
+
+		templates := []struct {
+			name string
+			t    *TemplateString
+		}{{"model", d.Model}, {"notifyTemplate", d.NotifyTemplate}}
+
+		for _, tmpl := range templates {
+			if tmpl.t == nil || tmpl.t.template == nil {
+				continue
+			}
+
+			buf := new(bytes.Buffer)
+			if err := tmpl.t.template.Execute(buf, params); err != nil {
+				errs = append(errs, errors.New(`error rendering `+tmpl.name+` of destination "`+d.Name+`" of `+d.source+`: `+err.Error()))
+
+				continue
+			}
+
+			if tmpl.name == "model" && strings.TrimSpace(buf.String()) == "" {
+				errs = append(errs, errors.New(`empty filename rendered by the model of destination "`+d.Name+`" of `+d.source))
+			}
+		}
+	}
+
+	return errs
+}
+
+// sampleParams returns the params of an upload filled with the initial value,
+// the example or the name of each field.
+func sampleParams(d Destination) map[string]string {
+	params := map[string]string{
+		"originalFilename": "example.pdf",
+		"filename":         "example.pdf",
+		"fileSize":         "1024",
+		"uploadedBy":       "user",
+	}
+
+	for name, f := range d.Fields {
+		example := ""
+		if f.Example != nil && f.Example.template != nil {
+			example = f.Example.String()
+		}
+
+		params[name] = cmp.Or(f.Value, example, name)
+	}
+
+	return params
+}
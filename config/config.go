@@ -6,12 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
-	"sync"
+	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/Masterminds/sprig/v3"
 	"github.com/go-playground/validator/v10"
@@ -23,11 +25,7 @@ const (
 	MAX_SIZE_LIMIT = 100 << 20 // 100 MB
 )
 
-var (
-	ErrWrongFileExt = errors.New("wrong file extension")
-
-	mu = new(sync.RWMutex)
-)
+var ErrWrongFileExt = errors.New("wrong file extension")
 
 type (
 	Config struct {
@@ -41,6 +39,31 @@ type (
 		URLPrefix    string        `yaml:"urlPrefix,omitempty" json:"urlPrefix,omitempty"`
 		Auth         Auth          `yaml:"auth" json:"auth"`
 		SMTPconfig   *SMTPConfig   `yaml:"smtpConfig,omitempty" json:"smtpConfig,omitempty"`
+		APITokens    []APIToken    `yaml:"apiTokens,omitempty" json:"apiTokens,omitempty" validate:"dive"`
+		Include      Includes      `yaml:"include,omitempty" json:"include,omitempty"`
+		Defaults     *Defaults     `yaml:"defaults,omitempty" json:"defaults,omitempty"`
+		file         string
+	}
+
+	// Includes are glob patterns of files with more destinations, relative
+	// to the config file. A single pattern can be given as a string.
+	Includes []string
+
+	// Defaults are inherited by the destinations without their own values.
+	Defaults struct {
+		AllowedTypes   []string        `yaml:"allowedTypes,omitempty" json:"allowedTypes,omitempty"`
+		MaxUploadSize  int64           `yaml:"maxUploadSize,omitempty" json:"maxUploadSize,omitempty" validate:"omitempty,min=1024"`
+		WebHook        *WebHook        `yaml:"webhook,omitempty" json:"webhook,omitempty"`
+		NotifyTemplate *TemplateString `yaml:"notifyTemplate,omitempty" json:"notifyTemplate,omitempty"`
+		AllowedRoles   []string        `yaml:"allowedRoles,omitempty" json:"allowedRoles,omitempty"`
+	}
+
+	// APIToken authenticates the requests to the API as the user Name. Only
+	// the SHA-256 of the token is stored.
+	APIToken struct {
+		Name      string   `yaml:"name" json:"name" validate:"required"`
+		TokenHash string   `yaml:"tokenHash" json:"tokenHash" validate:"required,len=64,hexadecimal"`
+		Roles     []string `yaml:"roles,omitempty" json:"roles,omitempty"`
 	}
 
 	Auth struct {
@@ -58,6 +81,7 @@ type (
 	}
 
 	Destination struct {
+		ID              string           `yaml:"id,omitempty" json:"id,omitempty" validate:"required"`
 		Name            string           `yaml:"name" json:"name" validate:"required"`
 		Bucket          string           `yaml:"bucket" json:"bucket" validate:"required"`
 		Prefix          string           `yaml:"prefix,omitempty" json:"prefix,omitempty"`
@@ -70,6 +94,36 @@ type (
 		Model           *TemplateString  `yaml:"model,omitempty" json:"model,omitempty"`
 		MaxResultLength int              `yaml:"maxResultLength,omitempty" json:"maxResultLength,omitempty" validate:"min=1,max=1000"`
 		MaxUploadSize   int64            `yaml:"maxUploadSize,omitempty" json:"maxUploadSize,omitempty" validate:"min=1024"`
+		ExtractArchives bool             `yaml:"extractArchives,omitempty" json:"extractArchives,omitempty"`
+		MaxArchiveSize  int64            `yaml:"maxArchiveSize,omitempty" json:"maxArchiveSize,omitempty" validate:"min=1024"`
+		Quota           *Quota           `yaml:"quota,omitempty" json:"quota,omitempty"`
+		UserLimits      *UserLimits      `yaml:"userLimits,omitempty" json:"userLimits,omitempty"`
+		Server          *Server          `yaml:"server,omitempty" json:"server,omitempty"`
+		MirrorTo        []string         `yaml:"mirrorTo,omitempty" json:"mirrorTo,omitempty" validate:"dive,required"`
+		MirrorDeletes   bool             `yaml:"mirrorDeletes,omitempty" json:"mirrorDeletes,omitempty"`
+		mirrors         []Destination
+		server          Server
+		source          string
+	}
+
+	// Server is an S3 endpoint other than the main one of the config.
+	Server struct {
+		Endpoint  string `yaml:"endpoint" json:"endpoint" validate:"required,hostname|hostname_port"`
+		Secure    bool   `yaml:"secure" json:"secure"`
+		AccessKey string `yaml:"accessKey" json:"accessKey" validate:"required"`
+		SecretKey string `yaml:"secretKey" json:"secretKey" validate:"required"`
+	}
+
+	Quota struct {
+		MaxBytes   int64 `yaml:"maxBytes,omitempty" json:"maxBytes,omitempty" validate:"min=0"`
+		MaxObjects int   `yaml:"maxObjects,omitempty" json:"maxObjects,omitempty" validate:"min=0"`
+	}
+
+	UserLimits struct {
+		MaxFiles         int   `yaml:"maxFiles,omitempty" json:"maxFiles,omitempty" validate:"min=0"`
+		MaxBytesPerDay   int64 `yaml:"maxBytesPerDay,omitempty" json:"maxBytesPerDay,omitempty" validate:"min=0"`
+		MaxBytesPerMonth int64 `yaml:"maxBytesPerMonth,omitempty" json:"maxBytesPerMonth,omitempty" validate:"min=0"`
+		MaxConcurrent    int   `yaml:"maxConcurrent,omitempty" json:"maxConcurrent,omitempty" validate:"min=0"`
 	}
 
 	Field struct {
@@ -96,7 +150,37 @@ type (
 	}
 )
 
+func (i *Includes) UnmarshalYAML(v *yaml.Node) error {
+	if v.Kind == yaml.ScalarNode {
+		*i = Includes{v.Value}
+
+		return nil
+	}
+
+	return v.Decode((*[]string)(i))
+}
+
+// UnmarshalYAML compiles the template. Its errors are type errors, so the
+// decoding goes on to report the errors of the other templates too.
 func (t *TemplateString) UnmarshalYAML(v *yaml.Node) error {
+	var err error
+	t.Value = v.Value
+	t.template, err = template.New("").Funcs(sprig.GenericFuncMap()).Parse(t.Value)
+	if err != nil {
+		return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: %v", v.Line, err)}}
+	}
+
+	return nil
+}
+
+func (t *TemplateString) UnmarshalJSON(b []byte) error {
+	v := struct {
+		Value string `json:"value"`
+	}{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
 	var err error
 	t.Value = v.Value
 	t.template, err = template.New("").Funcs(sprig.GenericFuncMap()).Parse(t.Value)
@@ -105,28 +189,79 @@ func (t *TemplateString) UnmarshalYAML(v *yaml.Node) error {
 }
 
 func (t TemplateString) String() string {
-	buf := new(bytes.Buffer)
-	if err := t.template.Execute(buf, t.Params); err != nil {
+	s, err := t.Render()
+	if err != nil {
 		slog.Error("error executing template", "error", err)
 
 		return t.Value
 	}
 
-	return buf.String()
+	return s
 }
 
-func (f Field) Validate() bool {
+// Render executes the template with Params.
+func (t TemplateString) Render() (string, error) {
+	buf := new(bytes.Buffer)
+	if err := t.template.Execute(buf, t.Params); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// UnmarshalJSON decodes fields received from the API, compiling the pattern.
+func (f *Field) UnmarshalJSON(b []byte) error {
+	type field Field
+	if err := json.Unmarshal(b, (*field)(f)); err != nil {
+		return err
+	}
+
 	if f.Pattern == "" {
+		return nil
+	}
+
+	var err error
+	f.regex, err = regexp.Compile(f.Pattern)
+
+	return err
+}
+
+func (f Field) Validate() bool {
+	// optional fields may be left empty whatever their pattern
+	if f.Pattern == "" || (f.Value == "" && !f.IsRequired) {
 		return true
 	}
 
 	return f.regex.MatchString(f.Value)
 }
 
-func (d Destination) MountName(params map[string]string) string {
+// MountName renders the model of the destination with params, failing if it
+// can't be executed or renders an empty name.
+func (d Destination) MountName(params map[string]string) (string, error) {
 	d.Model.Params = params
 
-	return d.Model.String()
+	name, err := d.Model.Render()
+	if err != nil {
+		return "", fmt.Errorf("error rendering the model: %w", err)
+	}
+
+	if strings.TrimSpace(name) == "" {
+		return "", errors.New("empty filename rendered by the model")
+	}
+
+	return name, nil
+}
+
+// Mirrors returns the destinations listed on MirrorTo, resolved by Parse.
+func (d Destination) Mirrors() []Destination {
+	return d.mirrors
+}
+
+// S3Server returns the server where the destination is stored (its Server or
+// the main one of the config), resolved by Parse. Destinations of a previous
+// config keep pointing to their servers after a reload.
+func (d Destination) S3Server() Server {
+	return d.server
 }
 
 func (c *Config) load(configFile string) error {
@@ -135,24 +270,42 @@ func (c *Config) load(configFile string) error {
 		return ErrWrongFileExt
 	}
 
-	f, err := os.Open(filepath.Clean(configFile))
-	if err != nil {
+	if err := decodeFile(configFile, c); err != nil {
 		return err
 	}
+	c.file = configFile
+	for i := range c.Destinations {
+		c.Destinations[i].source = configFile
+	}
 
-	if err := yaml.NewDecoder(f).Decode(c); err != nil {
-		return fmt.Errorf("error decoding config: %w", err)
+	if err := c.loadIncludes(); err != nil {
+		return err
+	}
+
+	if err := c.applyEnv(); err != nil {
+		return err
 	}
 
 	if c.Port == "" {
 		c.Port = "localhost:8000"
 	}
 
+	c.applyDefaults()
+
 	for i := range c.Destinations {
 		if c.Destinations[i].Name == "" {
 			c.Destinations[i].Name = c.Destinations[i].Bucket
 		}
 
+		if c.Destinations[i].ID == "" {
+			c.Destinations[i].ID = Slug(c.Destinations[i].Name)
+		}
+
+		c.Destinations[i].server = Server{Endpoint: c.Endpoint, Secure: c.Secure, AccessKey: c.AccessKey, SecretKey: c.SecretKey}
+		if c.Destinations[i].Server != nil {
+			c.Destinations[i].server = *c.Destinations[i].Server
+		}
+
 		if c.Destinations[i].MaxResultLength == 0 {
 			c.Destinations[i].MaxResultLength = MAX_RESULT_LEN
 		}
@@ -160,65 +313,268 @@ func (c *Config) load(configFile string) error {
 		if c.Destinations[i].MaxUploadSize == 0 {
 			c.Destinations[i].MaxUploadSize = MAX_SIZE_LIMIT
 		}
+
+		if c.Destinations[i].MaxArchiveSize == 0 {
+			c.Destinations[i].MaxArchiveSize = c.Destinations[i].MaxUploadSize
+		}
 	}
 
 	return nil
 }
 
+// decodeFile decodes a YAML file, replacing the environment variables of its
+// values.
+func decodeFile(file string, v any) error {
+	b, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return err
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(b, doc); err != nil {
+		return fmt.Errorf("error decoding config %s: %w", file, err)
+	}
+
+	if err := interpolateNode(doc); err != nil {
+		return fmt.Errorf("%w on %s", err, file)
+	}
+
+	if doc.Kind == 0 {
+		return nil
+	}
+
+	if err := doc.Decode(v); err != nil {
+		return fmt.Errorf("error decoding config %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// includedFiles returns the files matching Include, in lexical order. A
+// pattern matching no files is an error, but the matches of the others are
+// returned anyway.
+func (c *Config) includedFiles() ([]string, error) {
+	files, errs := []string{}, []error{}
+	for _, pattern := range c.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(c.file), pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid include %q of %s: %w", pattern, c.file, err))
+
+			continue
+		}
+
+		if len(matches) == 0 {
+			errs = append(errs, fmt.Errorf("include %q of %s matches no files", pattern, c.file))
+		}
+		files = append(files, matches...)
+	}
+
+	return files, errors.Join(errs...)
+}
+
+// loadIncludes appends the destinations of the included files.
+func (c *Config) loadIncludes() error {
+	files, err := c.includedFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		fragment := struct {
+			Destinations []Destination `yaml:"destinations"`
+		}{}
+		if err := decodeFile(file, &fragment); err != nil {
+			return err
+		}
+
+		for _, d := range fragment.Destinations {
+			d.source = file
+			c.Destinations = append(c.Destinations, d)
+		}
+	}
+
+	return nil
+}
+
+// applyDefaults sets the defaults on the destinations without their own
+// values. An empty list (as "allowedTypes: []") overrides a default list.
+func (c *Config) applyDefaults() {
+	if c.Defaults == nil {
+		return
+	}
+
+	for i, d := range c.Destinations {
+		if d.AllowedTypes == nil {
+			d.AllowedTypes = c.Defaults.AllowedTypes
+		}
+
+		if d.MaxUploadSize == 0 {
+			d.MaxUploadSize = c.Defaults.MaxUploadSize
+		}
+
+		if d.WebHook == nil {
+			d.WebHook = c.Defaults.WebHook
+		}
+
+		if d.NotifyTemplate == nil {
+			d.NotifyTemplate = c.Defaults.NotifyTemplate
+		}
+
+		if d.AllowedRoles == nil {
+			d.AllowedRoles = c.Defaults.AllowedRoles
+		}
+		c.Destinations[i] = d
+	}
+}
+
+// Files returns the config file and the files currently matching its
+// includes, which may differ from the loaded ones.
+func (c *Config) Files() []string {
+	included, _ := c.includedFiles()
+
+	return append([]string{c.file}, included...)
+}
+
 func (c *Config) Parse(configFile string) error {
 	if err := c.load(configFile); err != nil {
 		return err
 	}
 
+	return errors.Join(c.validate()...)
+}
+
+// validate returns all the problems of the config, compiling the patterns of
+// the fields and resolving the mirrors on the way.
+func (c *Config) validate() []error {
 	validate := validator.New(validator.WithRequiredStructEnabled())
+	errs := []error{}
+
+	if err := validate.StructExcept(c, "Destinations"); err != nil {
+		errs = append(errs, errors.New(`error validating config: "`+err.Error()+`"`))
+	}
 
-	if err := validate.Struct(c); err != nil {
-		return errors.New(`error validating config: "` + err.Error() + `"`)
+	if len(c.Destinations) == 0 {
+		errs = append(errs, errors.New(`error validating config: "no destinations"`))
 	}
 
-	names := []string{}
+	// without a driver, the roles of the requests are not checked at all
+	if len(c.APITokens) > 0 && c.Auth.Driver == "" {
+		errs = append(errs, errors.New(`error validating config: "apiTokens require an auth driver"`))
+	}
+
+	names, ids := []string{}, []string{}
 	for i, d := range c.Destinations {
+		if err := validate.Struct(d); err != nil {
+			errs = append(errs, errors.New(`error validating config: "`+err.Error()+`" on destination "`+d.Name+`" of `+d.source))
+		}
+
 		if slices.Contains(names, d.Name) {
-			return errors.New("duplicate destination name: " + d.Name)
+			errs = append(errs, errors.New("duplicate destination name: "+d.Name+" on "+d.source))
 		}
 		names = append(names, d.Name)
 
-		if len(d.Fields) == 0 {
+		if d.ID != Slug(d.ID) {
+			errs = append(errs, errors.New(`invalid id "`+d.ID+`" of destination "`+d.Name+`" on `+d.source+` (use lowercase letters, digits and "-")`))
+		}
+
+		if slices.Contains(ids, d.ID) {
+			errs = append(errs, errors.New("duplicate destination id: "+d.ID+" on "+d.source))
+		}
+		ids = append(ids, d.ID)
+
+		for _, fieldName := range slices.Sorted(maps.Keys(d.Fields)) {
+			f := d.Fields[fieldName]
+			if f.Pattern == "" {
+				continue
+			}
+
+			reg, err := regexp.Compile(f.Pattern)
+			if err != nil {
+				errs = append(errs, errors.New(err.Error()+` on pattern of field "`+fieldName+`" of destination "`+d.Name+`" of `+d.source))
+
+				continue
+			}
+			f.regex = reg
+			c.Destinations[i].Fields[fieldName] = f
+		}
+	}
+
+	return append(errs, c.resolveMirrors()...)
+}
+
+// Slug turns a destination name into its default ID, used on the URLs: the
+// letters in lower case and the digits, separated by "-".
+func Slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			dash = b.Len() > 0
+
 			continue
 		}
 
-		for fieldName, f := range d.Fields {
-			if f.Pattern != "" {
-				reg, err := regexp.Compile(f.Pattern)
-				if err != nil {
-					return errors.New(err.Error() + ` on pattern of destination "` + d.Name + `"`)
-				}
-				f.regex = reg
-				c.Destinations[i].Fields[fieldName] = f
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func (c *Config) resolveMirrors() []error {
+	errs := []error{}
+	byName := make(map[string]Destination, len(c.Destinations))
+	for _, d := range c.Destinations {
+		byName[d.Name] = d
+	}
+
+	for i, d := range c.Destinations {
+		for _, name := range d.MirrorTo {
+			m, ok := byName[name]
+			if !ok || name == d.Name {
+				errs = append(errs, errors.New(`invalid mirror "`+name+`" of destination "`+d.Name+`" on `+d.source))
+
+				continue
 			}
+			c.Destinations[i].mirrors = append(c.Destinations[i].mirrors, m)
 		}
 	}
 
-	return nil
+	return errs
 }
 
+// ToJSON returns the config without its secrets, including the values read
+// from the files of "_FILE" variables.
 func (c *Config) ToJSON() string {
-	mu.RLock()
-	defer mu.RUnlock()
-
-	b, err := json.Marshal(c) // #nosec G117
+	b, err := json.Marshal(c.redacted())
 	if err != nil {
 		return err.Error()
 	}
 
+	for _, s := range fileSecrets() {
+		quoted, _ := json.Marshal(s)
+		b = bytes.ReplaceAll(b, quoted[1:len(quoted)-1], []byte(REDACTED))
+	}
+
 	return string(b)
 }
 
+// ToYAML returns the config without its secrets, as ToJSON.
 func (c *Config) ToYAML() string {
-	mu.RLock()
-	defer mu.RUnlock()
+	doc := &yaml.Node{}
+	if err := doc.Encode(c.redacted()); err != nil {
+		return err.Error()
+	}
+	redactNode(doc, fileSecrets())
 
-	b, err := yaml.Marshal(c) // #nosec G117
+	b, err := yaml.Marshal(doc)
 	if err != nil {
 		return err.Error()
 	}
@@ -229,16 +585,3 @@ func (c *Config) ToYAML() string {
 func (c Config) String() string {
 	return c.ToYAML()
 }
-
-func (c *Config) ReloadDestinations(configFile string) error {
-	newCfg := &Config{}
-	if err := newCfg.Parse(configFile); err != nil {
-		return err
-	}
-
-	mu.Lock()
-	c.Destinations = newCfg.Destinations
-	mu.Unlock()
-
-	return nil
-}
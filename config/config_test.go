@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	defaults := &Defaults{AllowedTypes: []string{"pdf"}, MaxUploadSize: 2048, AllowedRoles: []string{"staff"}}
+
+	tests := []struct {
+		name     string
+		defaults *Defaults
+		dest     Destination
+		want     Destination
+	}{
+		{
+			"no defaults",
+			nil,
+			Destination{Name: "a"},
+			Destination{Name: "a"},
+		},
+		{
+			"inherited",
+			defaults,
+			Destination{Name: "a"},
+			Destination{Name: "a", AllowedTypes: []string{"pdf"}, MaxUploadSize: 2048, AllowedRoles: []string{"staff"}},
+		},
+		{
+			"own values",
+			defaults,
+			Destination{Name: "a", AllowedTypes: []string{"png"}, MaxUploadSize: 4096, AllowedRoles: []string{"admin"}},
+			Destination{Name: "a", AllowedTypes: []string{"png"}, MaxUploadSize: 4096, AllowedRoles: []string{"admin"}},
+		},
+		{
+			"empty lists override",
+			defaults,
+			Destination{Name: "a", AllowedTypes: []string{}, AllowedRoles: []string{}},
+			Destination{Name: "a", AllowedTypes: []string{}, MaxUploadSize: 2048, AllowedRoles: []string{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Defaults: tt.defaults, Destinations: []Destination{tt.dest}}
+			c.applyDefaults()
+
+			if got := c.Destinations[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIncludedFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"conf.d/a.yml", "conf.d/b.yml", "conf.d/c.txt", "other/d.yml"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configFile := filepath.Join(dir, "config.yml")
+
+	tests := []struct {
+		name    string
+		include Includes
+		want    []string
+		wantErr string
+	}{
+		{"none", nil, []string{}, ""},
+		{"relative glob", Includes{"conf.d/*.yml"}, []string{"conf.d/a.yml", "conf.d/b.yml"}, ""},
+		{"many patterns", Includes{"conf.d/b.yml", "other/*.yml"}, []string{"conf.d/b.yml", "other/d.yml"}, ""},
+		{"absolute glob", Includes{filepath.Join(dir, "other", "*")}, []string{"other/d.yml"}, ""},
+		{"no matches", Includes{"missing/*.yml"}, []string{}, "matches no files"},
+		{"no matches among others", Includes{"missing/*.yml", "other/*.yml"}, []string{"other/d.yml"}, "matches no files"},
+		{"invalid pattern", Includes{"conf.d/[.yml"}, []string{}, "invalid include"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Include: tt.include, file: configFile}
+			got, err := c.includedFiles()
+
+			want := make([]string, len(tt.want))
+			for i, f := range tt.want {
+				want[i] = filepath.Join(dir, f)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("includedFiles() = %q, want %q", got, want)
+			}
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("includedFiles() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), configFile)):
+				t.Errorf("includedFiles() error = %v, want %q naming %s", err, tt.wantErr, configFile)
+			}
+		})
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", ""},
+		{"docs", "docs"},
+		{"Public Docs", "public-docs"},
+		{"  Reports 2024  ", "reports-2024"},
+		{"a--b__c", "a-b-c"},
+		{"Relatórios Técnicos", "relatórios-técnicos"},
+		{"!!!", ""},
+		{"v1.2/final", "v1-2-final"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Slug(tt.name); got != tt.want {
+				t.Errorf("Slug(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ENV_PREFIX = "MINIOUP_"
+	REDACTED   = "********"
+)
+
+var (
+	ErrUndefinedVar = errors.New("undefined environment variable")
+
+	varRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+)
+
+// interpolateNode replaces the variables of the scalar values of the YAML
+// tree. Keys and comments are left as they are, and the values can't change
+// the structure of the document.
+func interpolateNode(n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		v, err := interpolate(n.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+
+		if v != n.Value {
+			n.Value = v
+			// the type of plain values is resolved again (as a port number)
+			if n.Style&(yaml.TaggedStyle|yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				n.Tag = ""
+			}
+		}
+
+		return nil
+	case yaml.MappingNode:
+		errs := []error{}
+		for i := 1; i < len(n.Content); i += 2 {
+			errs = append(errs, interpolateNode(n.Content[i]))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	errs := []error{}
+	for _, c := range n.Content {
+		errs = append(errs, interpolateNode(c))
+	}
+
+	return errors.Join(errs...)
+}
+
+// interpolate replaces "${VAR}" (or "${VAR:-default}") on s by the value of
+// the environment variable VAR or by the content of the file named by
+// VAR_FILE.
+func interpolate(s string) (string, error) {
+	errs := []error{}
+	s = varRegex.ReplaceAllStringFunc(s, func(m string) string {
+		sub := varRegex.FindStringSubmatch(m)
+		v, ok, err := lookupEnv(sub[1])
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		switch {
+		case ok:
+			return v
+		case sub[2] != "":
+			return sub[3]
+		}
+
+		if err == nil {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrUndefinedVar, sub[1]))
+		}
+
+		return m
+	})
+
+	return s, errors.Join(errs...)
+}
+
+// lookupEnv returns the value of the variable name or, as the secrets of
+// containers, the content of the file named by the variable name + "_FILE".
+func lookupEnv(name string) (string, bool, error) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, true, nil
+	}
+
+	file, ok := os.LookupEnv(name + "_FILE")
+	if !ok {
+		return "", false, nil
+	}
+
+	b, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return "", false, fmt.Errorf("error reading %s_FILE: %w", name, err)
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), true, nil
+}
+
+// applyEnv overrides the settings of the config (but not the destinations)
+// by the environment variables named by their keys with the prefix MINIOUP_,
+// as MINIOUP_SECRET_KEY, MINIOUP_SMTP_CONFIG_PASS or MINIOUP_AUTH_PARAMS_SECRET
+// (also accepting the "_FILE" suffix).
+func (c *Config) applyEnv() error {
+	return applyEnv(reflect.ValueOf(c).Elem(), ENV_PREFIX)
+}
+
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + envName(tag)
+		fv := v.Field(i)
+
+		switch fv.Kind() {
+		case reflect.Struct:
+			if err := applyEnv(fv, name+"_"); err != nil {
+				return err
+			}
+		case reflect.Pointer:
+			if fv.Type().Elem().Kind() != reflect.Struct || !hasEnvPrefix(name+"_") {
+				continue
+			}
+			if fv.IsNil() {
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			if err := applyEnv(fv.Elem(), name+"_"); err != nil {
+				return err
+			}
+		case reflect.Map:
+			if fv.Type().Key().Kind() == reflect.String && fv.Type().Elem().Kind() == reflect.String {
+				if err := applyEnvMap(fv, name+"_"); err != nil {
+					return err
+				}
+			}
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64:
+			s, ok, err := lookupEnv(name)
+			if err != nil {
+				return err
+			}
+			if ok {
+				if err := setValue(fv, s); err != nil {
+					return fmt.Errorf("invalid value of %s: %w", name, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyEnvMap sets the keys of a map (as auth params) by the variables with
+// the prefix, matching the existing keys ignoring case and underscores.
+func applyEnvMap(m reflect.Value, prefix string) error {
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		key, ok := strings.CutPrefix(name, prefix)
+		if !ok || key == "" {
+			continue
+		}
+
+		file := false
+		if k, ok := strings.CutSuffix(key, "_FILE"); ok {
+			key, file = k, true
+		}
+
+		s, _, err := lookupEnv(prefix + key)
+		if err != nil {
+			return err
+		}
+		if file {
+			// the plain variable has precedence
+			if _, ok := os.LookupEnv(prefix + key); ok {
+				continue
+			}
+		}
+
+		if m.IsNil() {
+			m.Set(reflect.MakeMap(m.Type()))
+		}
+
+		mapKey := strings.ToLower(key)
+		for _, k := range m.MapKeys() {
+			if strings.EqualFold(strings.ReplaceAll(key, "_", ""), k.String()) {
+				mapKey = k.String()
+			}
+		}
+		m.SetMapIndex(reflect.ValueOf(mapKey), reflect.ValueOf(s))
+	}
+
+	return nil
+}
+
+func hasEnvPrefix(prefix string) bool {
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func setValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	default:
+		v.SetString(s)
+	}
+
+	return nil
+}
+
+// envName converts a key as "accessKey" into "ACCESS_KEY".
+func envName(key string) string {
+	b := new(strings.Builder)
+	for i, r := range key {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	return b.String()
+}
+
+// redacted returns a copy of the config without its secrets.
+func (c *Config) redacted() Config {
+	r := *c
+	r.SecretKey = REDACTED
+
+	if c.SMTPconfig != nil {
+		smtp := *c.SMTPconfig
+		if smtp.Pass != "" {
+			smtp.Pass = REDACTED
+		}
+		r.SMTPconfig = &smtp
+	}
+
+	r.Auth.Params = maps.Clone(c.Auth.Params)
+	for k := range r.Auth.Params {
+		if isSecretParam(k) {
+			r.Auth.Params[k] = REDACTED
+		}
+	}
+
+	r.APITokens = make([]APIToken, len(c.APITokens))
+	for i, t := range c.APITokens {
+		t.TokenHash = REDACTED
+		r.APITokens[i] = t
+	}
+
+	if c.Defaults != nil {
+		defaults := *c.Defaults
+		defaults.WebHook = redactedWebHook(c.Defaults.WebHook)
+		r.Defaults = &defaults
+	}
+
+	r.Destinations = make([]Destination, len(c.Destinations))
+	for i, d := range c.Destinations {
+		if d.Server != nil {
+			s := *d.Server
+			s.SecretKey = REDACTED
+			d.Server = &s
+		}
+		d.WebHook = redactedWebHook(d.WebHook)
+		r.Destinations[i] = d
+	}
+
+	return r
+}
+
+// isSecretParam tells if an auth param holds a credential, by its key (as
+// "secret", "clientSecret", "bindPassword" or "token").
+func isSecretParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range []string{"secret", "pass", "token", "credential"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fileSecrets returns the contents of the files named by the "_FILE"
+// variables, which may have been interpolated anywhere on the config.
+func fileSecrets() []string {
+	secrets := []string{}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutSuffix(name, "_FILE")
+		if !ok || name == "" {
+			continue
+		}
+
+		if v, ok, err := lookupEnv(name); err == nil && ok && v != "" {
+			secrets = append(secrets, v)
+		}
+	}
+
+	return secrets
+}
+
+// redactNode hides the secrets on the scalar values of a YAML tree.
+func redactNode(n *yaml.Node, secrets []string) {
+	if n.Kind == yaml.ScalarNode {
+		v := n.Value
+		for _, s := range secrets {
+			v = strings.ReplaceAll(v, s, REDACTED)
+		}
+
+		if v != n.Value {
+			n.Value, n.Tag = v, "!!str"
+		}
+	}
+
+	for _, c := range n.Content {
+		redactNode(c, secrets)
+	}
+}
+
+// redactedWebHook hides the headers, the fields and the URL but its host, as
+// all of them commonly carry tokens.
+func redactedWebHook(wh *WebHook) *WebHook {
+	if wh == nil {
+		return nil
+	}
+
+	r := *wh
+	r.URL = REDACTED
+	if u, err := url.Parse(wh.URL); err == nil && u.Host != "" {
+		r.URL = u.Scheme + "://" + u.Host + "/" + REDACTED
+	}
+
+	r.Headers = make(map[string]string, len(wh.Headers))
+	for k := range wh.Headers {
+		r.Headers[k] = REDACTED
+	}
+
+	r.Fields = make(map[string]string, len(wh.Fields))
+	for k := range wh.Fields {
+		r.Fields[k] = REDACTED
+	}
+
+	return &r
+}
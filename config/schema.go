@@ -0,0 +1,167 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const SCHEMA_URI = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	configType         = reflect.TypeFor[Config]()
+	templateStringType = reflect.TypeFor[TemplateString]()
+	includesType       = reflect.TypeFor[Includes]()
+
+	// envValue accepts the variables replaced on loading (as "${PORT}") where
+	// numbers and booleans are expected.
+	envValue = map[string]any{"type": "string", "pattern": `^\$\{[^}]+\}$`}
+
+	// required fields that may be missing from a file: filled with defaults,
+	// taken from the environment or from the included files on loading
+	notRequired = []string{
+		"Config.Port", "Config.Endpoint", "Config.AccessKey", "Config.SecretKey", "Config.Destinations",
+		"Destination.ID", "Destination.Name",
+	}
+)
+
+// Schema returns the JSON Schema of the config file, made from the YAML keys
+// and the validations of the fields, to autocomplete and lint it on editors.
+// Other keys are accepted on the root, as the loader ignores them (to hold
+// YAML anchors, for instance).
+func Schema() map[string]any {
+	defs := map[string]any{}
+	schema := schemaOf(configType, defs)
+	delete(schema, "additionalProperties")
+	schema["$schema"] = SCHEMA_URI
+	schema["title"] = "minioUp config"
+	schema["$defs"] = defs
+
+	return schema
+}
+
+// FragmentSchema returns the JSON Schema of the files included by the config,
+// which have only a list of destinations.
+func FragmentSchema() map[string]any {
+	defs := map[string]any{}
+	destinations := schemaOf(reflect.TypeFor[[]Destination](), defs)
+
+	return map[string]any{
+		"$schema":              SCHEMA_URI,
+		"title":                "minioUp config fragment",
+		"type":                 "object",
+		"properties":           map[string]any{"destinations": destinations},
+		"required":             []string{"destinations"},
+		"additionalProperties": false,
+		"$defs":                defs,
+	}
+}
+
+func schemaOf(t reflect.Type, defs map[string]any) map[string]any {
+	switch t {
+	case templateStringType:
+		return map[string]any{"type": "string"}
+	case includesType:
+		return map[string]any{"anyOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		}}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem(), defs)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"anyOf": []any{map[string]any{"type": "boolean"}, envValue}}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"anyOf": []any{map[string]any{"type": "integer"}, envValue}}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), defs)}
+	case reflect.Struct:
+		if t == configType {
+			return structSchema(t, defs)
+		}
+
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = structSchema(t, defs)
+		}
+
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+
+	return map[string]any{}
+}
+
+func structSchema(t reflect.Type, defs map[string]any) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "" || name == "-" {
+			continue
+		}
+
+		prop := schemaOf(f.Type, defs)
+		rules := strings.Split(f.Tag.Get("validate"), ",")
+		applyRules(prop, f.Type, rules)
+		properties[name] = prop
+
+		// the rules after "dive" are of the items
+		if i := slices.Index(rules, "dive"); i >= 0 {
+			rules = rules[:i]
+		}
+
+		if slices.Contains(rules, "required") && !slices.Contains(notRequired, t.Name()+"."+f.Name) {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+// applyRules translates the validations of a field, when there is an
+// equivalent on JSON Schema.
+func applyRules(prop map[string]any, t reflect.Type, rules []string) {
+	numeric := t.Kind() == reflect.Int || t.Kind() == reflect.Int64
+	for i, rule := range rules {
+		name, value, _ := strings.Cut(rule, "=")
+		switch {
+		case name == "dive":
+			if items, ok := prop["items"].(map[string]any); ok {
+				applyRules(items, t.Elem(), rules[i+1:])
+			}
+
+			return
+		case name == "email":
+			prop["format"] = "email"
+		case name == "url":
+			prop["format"] = "uri"
+		case name == "len" && t.Kind() == reflect.String:
+			n, _ := strconv.Atoi(value)
+			prop["minLength"], prop["maxLength"] = n, n
+		case name == "hexadecimal":
+			prop["pattern"] = "^[0-9a-fA-F]*$"
+		case (name == "min" || name == "max") && numeric:
+			n, _ := strconv.Atoi(value)
+			key := map[string]string{"min": "minimum", "max": "maximum"}[name]
+			anyOf := prop["anyOf"].([]any)
+			anyOf[0].(map[string]any)[key] = n
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// missing is the value of a path not found on the schema
+type missing struct{}
+
+// schemaValue follows a path as "properties.port.type" on the schema, with
+// numbers indexing arrays.
+func schemaValue(schema map[string]any, path string) any {
+	var v any = schema
+	for key := range strings.SplitSeq(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return missing{}
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i >= len(node) {
+				return missing{}
+			}
+			v = node[i]
+		default:
+			return missing{}
+		}
+	}
+
+	return v
+}
+
+func TestSchema(t *testing.T) {
+	schema, fragment := Schema(), FragmentSchema()
+
+	tests := []struct {
+		name   string
+		schema map[string]any
+		path   string
+		want   any
+	}{
+		{"dialect", schema, "$schema", SCHEMA_URI},
+		{"other keys accepted on the root", schema, "additionalProperties", missing{}},
+		{"root keys filled on loading", schema, "required", missing{}},
+		{"string", schema, "properties.port.type", "string"},
+		{"boolean or variable", schema, "properties.secure.anyOf.1.pattern", `^\$\{[^}]+\}$`},
+		{"include as string", schema, "properties.include.anyOf.0.type", "string"},
+		{"include as list", schema, "properties.include.anyOf.1.type", "array"},
+		{"struct reference", schema, "properties.smtpConfig.$ref", "#/$defs/SMTPConfig"},
+		{"list of references", schema, "properties.destinations.items.$ref", "#/$defs/Destination"},
+		{"map", schema, "properties.auth.$ref", "#/$defs/Auth"},
+		{"map values", schema, "$defs.Auth.properties.params.additionalProperties.type", "string"},
+		{"unknown keys of definitions", schema, "$defs.Destination.additionalProperties", false},
+		{"required", schema, "$defs.Destination.required", []string{"bucket"}},
+		{"required of other definitions", schema, "$defs.SMTPConfig.required", []string{"host", "port", "from"}},
+		{"email", schema, "$defs.SMTPConfig.properties.from.format", "email"},
+		{"email items", schema, "$defs.Destination.properties.notifyEmails.items.format", "email"},
+		{"minimum", schema, "$defs.SMTPConfig.properties.port.anyOf.0.minimum", 1},
+		{"maximum", schema, "$defs.SMTPConfig.properties.port.anyOf.0.maximum", 65535},
+		{"length", schema, "$defs.APIToken.properties.tokenHash.minLength", 64},
+		{"hexadecimal", schema, "$defs.APIToken.properties.tokenHash.pattern", "^[0-9a-fA-F]*$"},
+		{"template", schema, "$defs.Destination.properties.notifyTemplate.type", "string"},
+		{"fragment dialect", fragment, "$schema", SCHEMA_URI},
+		{"fragment keys", fragment, "additionalProperties", false},
+		{"fragment required", fragment, "required", []string{"destinations"}},
+		{"fragment destinations", fragment, "properties.destinations.items.$ref", "#/$defs/Destination"},
+		{"fragment definitions", fragment, "$defs.Destination.required", []string{"bucket"}},
+		{"fragment without root keys", fragment, "properties.port", missing{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schemaValue(tt.schema, tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	for _, s := range []map[string]any{schema, fragment} {
+		if _, err := json.Marshal(s); err != nil {
+			t.Errorf("error encoding %s: %v", s["title"], err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hitalos/minioUp/config"
+	"github.com/hitalos/minioUp/services/minioClient"
+	"github.com/hitalos/minioUp/services/smtpClient"
+)
+
+const TIMEOUT = 10 * time.Second
+
+// ConfigFile prints to w all the problems of the config file (with connect,
+// also of its buckets and SMTP server) as "FAIL" lines, or an "OK" line.
+func ConfigFile(w io.Writer, path string, connect bool) bool {
+	cfg, errs := config.Check(path)
+	if len(errs) == 0 && connect {
+		errs = Connections(*cfg)
+	}
+
+	for _, err := range errs {
+		_, _ = fmt.Fprintf(w, "FAIL\t%s\t%v\n", path, err)
+	}
+
+	if len(errs) > 0 {
+		return false
+	}
+
+	_, _ = fmt.Fprintf(w, "OK\t%s\n", path)
+
+	return true
+}
+
+// Connections reports the buckets that don't exist or can't be reached and
+// whether the SMTP server can't be reached.
+func Connections(cfg config.Config) []error {
+	if err := minioClient.Init(cfg); err != nil {
+		return []error{err}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), TIMEOUT)
+	defer cancel()
+
+	errs := minioClient.CheckBuckets(ctx, cfg)
+	if cfg.SMTPconfig != nil {
+		if err := smtpClient.Check(ctx, *cfg.SMTPconfig); err != nil {
+			errs = append(errs, fmt.Errorf("error connecting to SMTP server %q: %w", cfg.SMTPconfig.Host, err))
+		}
+	}
+
+	return errs
+}
+
+// WriteSchema writes the JSON Schema of the config file or, with fragment, of
+// the files it includes.
+func WriteSchema(w io.Writer, fragment bool) error {
+	schema := config.Schema()
+	if fragment {
+		schema = config.FragmentSchema()
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(schema)
+}
@@ -0,0 +1,190 @@
+package minioClient
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/hitalos/minioUp/config"
+)
+
+const MAX_ARCHIVE_ENTRIES = 1000
+
+var (
+	ErrUnsafePath        = errors.New("unsafe path in archive")
+	ErrTooManyEntries    = errors.New("too many entries in archive")
+	ErrUnsupportedFormat = errors.New("unsupported archive format")
+	ErrDuplicateKey      = errors.New("duplicate key in archive")
+)
+
+type (
+	// ArchiveFile is satisfied by *os.File and multipart.File.
+	ArchiveFile interface {
+		io.Reader
+		io.ReaderAt
+	}
+
+	EntryResult struct {
+		Name  string
+		Error error
+	}
+
+	// archiveUpload is the state shared by the entries of an archive, as the
+	// keys already written, to find entries overwriting each other.
+	archiveUpload struct {
+		dest     config.Destination
+		params   map[string]string
+		username string
+		keys     map[string]string
+	}
+)
+
+func IsArchive(filename string) bool {
+	name := strings.ToLower(filename)
+
+	return strings.HasSuffix(name, ".zip") || strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// UploadArchive extracts a ".zip" or ".tar.gz" archive uploading each file as
+// if it was sent alone, so AllowedTypes, MaxUploadSize and Model apply per
+// entry. Entries rendering the same key fail with ErrDuplicateKey, and the
+// quota and user limits are checked against the cached usage of the
+// destination instead of listing it for each entry. Failures of single entries are reported on the results.
+func UploadArchive(ctx context.Context, dest config.Destination, f ArchiveFile, filename string, size int64, params map[string]string) ([]EntryResult, error) {
+	if size > dest.MaxArchiveSize {
+		return nil, fmt.Errorf("archive size exceeds the maximum allowed size of %d bytes", dest.MaxArchiveSize)
+	}
+
+	name := strings.ToLower(filename)
+	if !strings.HasSuffix(name, ".zip") && !strings.HasSuffix(name, ".tar.gz") && !strings.HasSuffix(name, ".tgz") {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, filepath.Base(filename))
+	}
+
+	a := &archiveUpload{dest: dest, params: params, username: params["uploadedBy"], keys: map[string]string{}}
+	if a.username != "" && dest.UserLimits != nil {
+		release, err := reserveSlot(dest, a.username)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	if strings.HasSuffix(name, ".zip") {
+		return a.uploadZip(ctx, f, size)
+	}
+
+	return a.uploadTarGz(ctx, f)
+}
+
+// upload sends one entry as Upload would, but checking its key against the
+// other entries.
+func (a *archiveUpload) upload(ctx context.Context, r io.Reader, filename string, size int64) error {
+	path, options, err := prepareUpload(a.dest, filename, size, maps.Clone(a.params))
+	if err != nil {
+		return err
+	}
+
+	if other, ok := a.keys[path]; ok {
+		return fmt.Errorf("%w: %q is also the key of %q", ErrDuplicateKey, RelativeKey(a.dest, path), other)
+	}
+
+	_, _, done, err := checkLimits(ctx, a.dest, path, a.username, size)
+	if err != nil {
+		return err
+	}
+	a.keys[path] = filename
+
+	err = send(ctx, a.dest, path, r, size, options, TransferOptions{})
+	done(size, err)
+
+	return err
+}
+
+func (a *archiveUpload) uploadZip(ctx context.Context, f io.ReaderAt, size int64) ([]EntryResult, error) {
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(zr.File) > MAX_ARCHIVE_ENTRIES {
+		return nil, fmt.Errorf("%w: %d", ErrTooManyEntries, len(zr.File))
+	}
+
+	results := []EntryResult{}
+	for _, entry := range zr.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		results = append(results, EntryResult{entry.Name, a.uploadZipEntry(ctx, entry)})
+	}
+
+	return results, nil
+}
+
+func (a *archiveUpload) uploadZipEntry(ctx context.Context, entry *zip.File) error {
+	if !filepath.IsLocal(entry.Name) {
+		return ErrUnsafePath
+	}
+
+	if !entry.Mode().IsRegular() {
+		return fmt.Errorf("%w: not a regular file", ErrUnsupportedFormat)
+	}
+
+	size := int64(entry.UncompressedSize64) // #nosec G115
+	if size > a.dest.MaxUploadSize || size < 0 {
+		return fmt.Errorf("%w of %d bytes", ErrTooLarge, a.dest.MaxUploadSize)
+	}
+
+	rc, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	return a.upload(ctx, rc, entry.Name, size)
+}
+
+func (a *archiveUpload) uploadTarGz(ctx context.Context, f io.Reader) ([]EntryResult, error) {
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = gz.Close() }()
+
+	results := []EntryResult{}
+	tr := tar.NewReader(gz)
+	for count := 0; ; count++ {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return results, err
+		}
+
+		if count >= MAX_ARCHIVE_ENTRIES {
+			return results, fmt.Errorf("%w: more than %d", ErrTooManyEntries, MAX_ARCHIVE_ENTRIES)
+		}
+
+		switch {
+		case hdr.Typeflag == tar.TypeDir:
+			continue
+		case !filepath.IsLocal(hdr.Name):
+			results = append(results, EntryResult{hdr.Name, ErrUnsafePath})
+		case hdr.Typeflag != tar.TypeReg:
+			results = append(results, EntryResult{hdr.Name, fmt.Errorf("%w: not a regular file", ErrUnsupportedFormat)})
+		default:
+			results = append(results, EntryResult{hdr.Name, a.upload(ctx, tr, hdr.Name, hdr.Size)})
+		}
+	}
+
+	return results, nil
+}
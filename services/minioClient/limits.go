@@ -0,0 +1,101 @@
+package minioClient
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/hitalos/minioUp/config"
+)
+
+type UserUsage struct {
+	Files          int
+	BytesToday     int64
+	BytesThisMonth int64
+}
+
+var (
+	ErrUserLimitExceeded = errors.New("user upload limit exceeded")
+
+	uploadsMu = new(sync.Mutex)
+	uploading = map[string]int{}
+)
+
+// UserUsageOf sums the objects of list that were uploaded by username.
+func UserUsageOf(list []minio.ObjectInfo, username string, now time.Time) UserUsage {
+	usage := UserUsage{}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	for _, obj := range list {
+		if MetadataValue(obj.UserMetadata, "uploadedBy") != username {
+			continue
+		}
+
+		usage.Files++
+		if !obj.LastModified.Before(month) {
+			usage.BytesThisMonth += obj.Size
+		}
+		if !obj.LastModified.Before(today) {
+			usage.BytesToday += obj.Size
+		}
+	}
+
+	return usage
+}
+
+func hasUserLimits(dest config.Destination) bool {
+	l := dest.UserLimits
+
+	return l != nil && (l.MaxFiles > 0 || l.MaxBytesPerDay > 0 || l.MaxBytesPerMonth > 0)
+}
+
+// reserveSlot takes one of the concurrent uploads allowed to username, which
+// must be freed calling release.
+func reserveSlot(dest config.Destination, username string) (release func(), err error) {
+	slot := storageKey(dest) + "/" + username
+
+	uploadsMu.Lock()
+	defer uploadsMu.Unlock()
+
+	if max := dest.UserLimits.MaxConcurrent; max > 0 && uploading[slot] >= max {
+		return nil, fmt.Errorf("%w: %d concurrent uploads", ErrUserLimitExceeded, max)
+	}
+	uploading[slot]++
+
+	return func() {
+		uploadsMu.Lock()
+		defer uploadsMu.Unlock()
+
+		if uploading[slot]--; uploading[slot] <= 0 {
+			delete(uploading, slot)
+		}
+	}, nil
+}
+
+// available checks that a new file of size bytes keeps the usage within the
+// limits, returning the bytes still allowed.
+func (u UserUsage) available(limits *config.UserLimits, size int64) (int64, error) {
+	switch {
+	case limits.MaxFiles > 0 && u.Files >= limits.MaxFiles:
+		return 0, fmt.Errorf("%w: %d of %d files", ErrUserLimitExceeded, u.Files, limits.MaxFiles)
+	case limits.MaxBytesPerDay > 0 && u.BytesToday+size > limits.MaxBytesPerDay:
+		return 0, fmt.Errorf("%w: %d of %d bytes today", ErrUserLimitExceeded, u.BytesToday, limits.MaxBytesPerDay)
+	case limits.MaxBytesPerMonth > 0 && u.BytesThisMonth+size > limits.MaxBytesPerMonth:
+		return 0, fmt.Errorf("%w: %d of %d bytes this month", ErrUserLimitExceeded, u.BytesThisMonth, limits.MaxBytesPerMonth)
+	}
+
+	available := int64(math.MaxInt64)
+	if limits.MaxBytesPerDay > 0 {
+		available = limits.MaxBytesPerDay - u.BytesToday
+	}
+	if limits.MaxBytesPerMonth > 0 {
+		available = min(available, limits.MaxBytesPerMonth-u.BytesThisMonth)
+	}
+
+	return available, nil
+}
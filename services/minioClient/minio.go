@@ -1,13 +1,22 @@
 package minioClient
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"math"
 	"mime"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -15,46 +24,407 @@ import (
 	"github.com/hitalos/minioUp/config"
 )
 
-var client *minio.Client
+type Usage struct {
+	Bytes   int64
+	Objects int
+}
+
+var (
+	ErrObjectExists  = errors.New("object already exists")
+	ErrInvalidPrefix = errors.New("invalid prefix")
+	ErrInvalidKey    = errors.New("invalid key")
+	ErrMissingField  = errors.New("missing required field")
+	ErrQuotaExceeded = errors.New("destination quota exceeded")
+	ErrTooLarge      = errors.New("file size exceeds the maximum allowed size")
+
+	clients   = map[config.Server]*minio.Client{}
+	clientsMu = new(sync.RWMutex)
+)
 
+// Init creates the clients of the servers of the config. Called again (on a
+// reload), the pool is replaced at once and only if every client is created.
+// It holds only the servers of the new config, reusing their clients.
 func Init(cfg config.Config) error {
-	var err error
+	clientsMu.RLock()
+	old := clients
+	clientsMu.RUnlock()
 
-	creds := credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, "")
-	client, err = minio.New(cfg.Endpoint, &minio.Options{Secure: cfg.Secure, Creds: creds})
+	pool := map[config.Server]*minio.Client{}
+	for _, d := range cfg.Destinations {
+		s := d.S3Server()
+		if _, ok := pool[s]; ok {
+			continue
+		}
 
-	return err
+		if c, ok := old[s]; ok {
+			pool[s] = c
+
+			continue
+		}
+
+		c, err := newClient(s)
+		if err != nil {
+			return fmt.Errorf("error creating client for %q: %w", s.Endpoint, err)
+		}
+		pool[s] = c
+	}
+
+	clientsMu.Lock()
+	clients = pool
+	clientsMu.Unlock()
+
+	return nil
 }
 
-func UploadMultiple(ctx context.Context, dest config.Destination, filepaths []string, params []map[string]string) error {
-	for idx, file := range filepaths {
-		f, err := os.Open(filepath.Clean(file))
+func newClient(s config.Server) (*minio.Client, error) {
+	creds := credentials.NewStaticV4(s.AccessKey, s.SecretKey, "")
+
+	return minio.New(s.Endpoint, &minio.Options{Secure: s.Secure, Creds: creds})
+}
+
+// clientOf returns the client of the server where the destination is stored.
+// Destinations of requests still using a previous config may be on servers
+// out of the pool, which get a client of their own, not kept.
+func clientOf(dest config.Destination) (*minio.Client, error) {
+	s := dest.S3Server()
+
+	clientsMu.RLock()
+	c, ok := clients[s]
+	clientsMu.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	c, err := newClient(s)
+	if err != nil {
+		return nil, fmt.Errorf("error creating client for %q: %w", s.Endpoint, err)
+	}
+
+	return c, nil
+}
+
+// CheckBuckets reports the destinations whose bucket doesn't exist or can't be
+// reached. Init must be called first.
+func CheckBuckets(ctx context.Context, cfg config.Config) []error {
+	errs := []error{}
+	for _, d := range cfg.Destinations {
+		c, err := clientOf(d)
 		if err != nil {
-			return err
+			errs = append(errs, err)
+
+			continue
 		}
-		stat, _ := f.Stat()
 
-		if err = Upload(ctx, dest, f, file, stat.Size(), params[idx]); err != nil {
-			return err
+		exists, err := c.BucketExists(ctx, d.Bucket)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("error checking bucket %q of destination %q: %w", d.Bucket, d.Name, err))
+		case !exists:
+			errs = append(errs, fmt.Errorf("bucket %q of destination %q not found", d.Bucket, d.Name))
 		}
-		_ = f.Close()
 	}
 
-	return nil
+	return errs
+}
+
+// UploadMultiple uploads the files using up to concurrency simultaneous
+// uploads. params holds the params of each file, at the same index. The
+// results keep the order of filepaths.
+func UploadMultiple(ctx context.Context, dest config.Destination, filepaths []string, params []map[string]string, concurrency int, transfer TransferOptions) []EntryResult {
+	results := make([]EntryResult, len(filepaths))
+	sem := make(chan struct{}, max(concurrency, 1))
+	wg := new(sync.WaitGroup)
+
+	for idx, file := range filepaths {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			results[idx] = EntryResult{file, uploadFile(ctx, dest, file, maps.Clone(params[idx]), transfer)}
+		})
+	}
+	wg.Wait()
+
+	return results
 }
 
-func Upload(ctx context.Context, dest config.Destination, r io.Reader, filename string, size int64, params map[string]string) error {
+func uploadFile(ctx context.Context, dest config.Destination, file string, params map[string]string, transfer TransferOptions) error {
+	f, err := os.Open(filepath.Clean(file))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("file not found: %s", file)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if stat.IsDir() {
+		return fmt.Errorf("%s is a directory, not a file", file)
+	}
+
+	if params == nil {
+		params = map[string]string{}
+	}
+
+	return Upload(ctx, dest, f, file, stat.Size(), params, transfer)
+}
+
+func Upload(ctx context.Context, dest config.Destination, r io.Reader, filename string, size int64, params map[string]string, transfer TransferOptions) error {
+	path, options, err := prepareUpload(dest, filename, size, params)
+	if err != nil {
+		return err
+	}
+
+	return put(ctx, dest, path, r, size, options, transfer)
+}
+
+// prepareUpload validates an upload of filename, returning the key and the
+// options of its object.
+func prepareUpload(dest config.Destination, filename string, size int64, params map[string]string) (string, minio.PutObjectOptions, error) {
 	originalFilename := filepath.Base(filename)
 
+	if err := validateUpload(dest, originalFilename, size, params); err != nil {
+		return "", minio.PutObjectOptions{}, err
+	}
+
+	options := minio.PutObjectOptions{
+		UserMetadata: params,
+		ContentType:  mime.TypeByExtension(filepath.Ext(filename)),
+	}
+
+	options.UserMetadata["originalFilename"] = originalFilename
+
+	path, err := objectPath(dest, originalFilename, options.UserMetadata)
+	if err != nil {
+		return "", minio.PutObjectOptions{}, err
+	}
+
+	return path, options, nil
+}
+
+// UploadPreview is where an upload would be written.
+type UploadPreview struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	Exists bool   `json:"exists"`
+}
+
+// Preview runs the validations of Upload, including the quota and the user
+// limits, and renders the key of the object without sending any data. Exists
+// reports if an object would be replaced.
+func Preview(ctx context.Context, dest config.Destination, filename string, size int64, params map[string]string) (UploadPreview, error) {
+	originalFilename := filepath.Base(filename)
+
+	if err := validateUpload(dest, originalFilename, size, params); err != nil {
+		return UploadPreview{}, err
+	}
+
+	metadata := maps.Clone(params)
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadata["originalFilename"] = originalFilename
+
+	key, err := objectPath(dest, originalFilename, metadata)
+	if err != nil {
+		return UploadPreview{}, err
+	}
+
+	if err := previewLimits(ctx, dest, key, metadata["uploadedBy"], max(size, 0)); err != nil {
+		return UploadPreview{}, err
+	}
+
+	_, err = statObject(ctx, dest, key)
+	if err != nil && minio.ToErrorResponse(err).Code != "NoSuchKey" {
+		return UploadPreview{}, err
+	}
+
+	return UploadPreview{Bucket: dest.Bucket, Key: key, Exists: err == nil}, nil
+}
+
+// UploadKey uploads r at key, relative to the destination prefix, instead of
+// naming the object by the destination model.
+func UploadKey(ctx context.Context, dest config.Destination, r io.Reader, key string, size int64, params map[string]string, transfer TransferOptions) error {
+	originalFilename := filepath.Base(key)
+
+	if err := validateUpload(dest, originalFilename, size, params); err != nil {
+		return err
+	}
+
+	options := minio.PutObjectOptions{
+		UserMetadata: params,
+		ContentType:  mime.TypeByExtension(filepath.Ext(key)),
+	}
+
+	options.UserMetadata["originalFilename"] = originalFilename
+
+	path, err := keyPath(dest, key)
+	if err != nil {
+		return err
+	}
+
+	return put(ctx, dest, path, r, size, options, transfer)
+}
+
+func validateUpload(dest config.Destination, filename string, size int64, params map[string]string) error {
 	if len(dest.AllowedTypes) > 0 {
-		ext := filepath.Ext(originalFilename)[1:]
+		ext := strings.TrimPrefix(filepath.Ext(filename), ".")
 		if !slices.Contains(dest.AllowedTypes, ext) {
 			return fmt.Errorf("invalid file type: %q", ext)
 		}
 	}
 
+	if err := validateParams(dest, params); err != nil {
+		return err
+	}
+
+	if size > dest.MaxUploadSize {
+		return fmt.Errorf("%w of %d bytes", ErrTooLarge, dest.MaxUploadSize)
+	}
+
+	return nil
+}
+
+// sizeLimitReader fails with err when more than remaining bytes are read, so
+// streams of unknown length can't exceed the destination limits.
+type sizeLimitReader struct {
+	r         io.Reader
+	read      int64
+	remaining int64
+	err       error
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, l.err
+	}
+
+	return n, err
+}
+
+// lower reduces the bytes allowed to available, failing with cause beyond them.
+func (l *sizeLimitReader) lower(available int64, cause error) {
+	if available < l.remaining {
+		l.remaining, l.err = available, fmt.Errorf("%w: streamed content beyond the %d bytes available", cause, available)
+	}
+}
+
+func put(ctx context.Context, dest config.Destination, path string, r io.Reader, size int64, options minio.PutObjectOptions, transfer TransferOptions) error {
+	// with an unknown size (-1), the content is sent as a multipart upload
+	// aborted when it goes beyond the smallest of the limits
+	limit := &sizeLimitReader{r: r, remaining: dest.MaxUploadSize, err: fmt.Errorf("%w of %d bytes", ErrTooLarge, dest.MaxUploadSize)}
+
+	username := options.UserMetadata["uploadedBy"]
+	if username != "" && dest.UserLimits != nil {
+		release, err := reserveSlot(dest, username)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	quota, user, done, err := checkLimits(ctx, dest, path, username, max(size, 0))
+	if err != nil {
+		return err
+	}
+	limit.lower(quota, ErrQuotaExceeded)
+	limit.lower(user, ErrUserLimitExceeded)
+
+	if size < 0 {
+		r = limit
+		// minio-go buffers whole parts of streams, by default of 512 MB
+		options.PartSize = RESUMABLE_PART_SIZE
+	}
+
+	err = send(ctx, dest, path, r, size, options, transfer)
+	if size < 0 {
+		done(limit.read, err)
+	} else {
+		done(size, err)
+	}
+
+	if err != nil && size < 0 && errors.Is(err, limit.err) {
+		return limit.err
+	}
+
+	return err
+}
+
+// send writes the object, already checked against the limits, and queues its
+// replication to the mirrors.
+func send(ctx context.Context, dest config.Destination, path string, r io.Reader, size int64, options minio.PutObjectOptions, transfer TransferOptions) error {
+	c, err := clientOf(dest)
+	if err != nil {
+		return err
+	}
+
+	if err := putObject(ctx, c, dest.Bucket, path, r, size, options, transfer); err != nil {
+		return err
+	}
+
+	mirror(dest, RelativeKey(dest, path), false)
+
+	return nil
+}
+
+// GetUsage sums the size and count of the objects stored under the
+// destination prefix.
+func GetUsage(ctx context.Context, dest config.Destination) (Usage, error) {
+	usage := Usage{}
+	c, err := clientOf(dest)
+	if err != nil {
+		return usage, err
+	}
+
+	opts := minio.ListObjectsOptions{Prefix: dest.Prefix, Recursive: true}
+	for obj := range c.ListObjects(ctx, dest.Bucket, opts) {
+		if obj.Err != nil {
+			return usage, obj.Err
+		}
+		usage.add(obj.Size)
+	}
+
+	return usage, nil
+}
+
+func (u *Usage) add(size int64) {
+	u.Bytes += size
+	u.Objects++
+}
+
+// available checks that a new object of size bytes keeps the usage within
+// the quota, returning the bytes still available.
+func (u Usage) available(q *config.Quota, size int64) (int64, error) {
+	if q.MaxBytes > 0 && u.Bytes+size > q.MaxBytes {
+		return 0, fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, u.Bytes, q.MaxBytes)
+	}
+
+	if q.MaxObjects > 0 && u.Objects+1 > q.MaxObjects {
+		return 0, fmt.Errorf("%w: %d of %d files stored", ErrQuotaExceeded, u.Objects, q.MaxObjects)
+	}
+
+	if q.MaxBytes == 0 {
+		return math.MaxInt64, nil
+	}
+
+	return q.MaxBytes - u.Bytes, nil
+}
+
+func validateParams(dest config.Destination, params map[string]string) error {
 	for k, f := range dest.Fields {
 		f.Value = params[k]
+		if f.Value == "" && f.IsRequired {
+			return fmt.Errorf("%w: %q", ErrMissingField, k)
+		}
+
 		if f.Validate() {
 			continue
 		}
@@ -62,36 +432,251 @@ func Upload(ctx context.Context, dest config.Destination, r io.Reader, filename
 		return fmt.Errorf("invalid value for field %q: %s", k, f.Value)
 	}
 
-	if size > dest.MaxUploadSize {
-		return fmt.Errorf("file size exceeds the maximum allowed size of %d bytes", dest.MaxUploadSize)
+	return nil
+}
+
+func objectPath(dest config.Destination, originalFilename string, metadata map[string]string) (string, error) {
+	if dest.Model != nil && dest.Model.Value != "" {
+		name, err := dest.MountName(metadata)
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Join(dest.Prefix, name), nil
 	}
 
-	options := minio.PutObjectOptions{
-		UserMetadata: params,
-		ContentType:  mime.TypeByExtension(filepath.Ext(filename)),
+	return filepath.Join(dest.Prefix, originalFilename), nil
+}
+
+// RelativeKey strips the destination prefix from an object key.
+func RelativeKey(dest config.Destination, key string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(key, dest.Prefix), "/")
+}
+
+func statObject(ctx context.Context, dest config.Destination, key string) (minio.ObjectInfo, error) {
+	c, err := clientOf(dest)
+	if err != nil {
+		return minio.ObjectInfo{}, err
 	}
 
-	options.UserMetadata["originalFilename"] = originalFilename
+	return c.StatObject(ctx, dest.Bucket, key, minio.StatObjectOptions{})
+}
 
-	path := filepath.Join(dest.Prefix, originalFilename)
-	if dest.Model != nil && dest.Model.Value != "" {
-		path = filepath.Join(dest.Prefix, dest.MountName(options.UserMetadata))
+func removeObject(ctx context.Context, dest config.Destination, key string) error {
+	c, err := clientOf(dest)
+	if err != nil {
+		return err
+	}
+
+	if err := c.RemoveObject(ctx, dest.Bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return err
+	}
+	uncache(dest, key)
+
+	return nil
+}
+
+// copyObject copies src from a destination to dst on another one. The copy is
+// done on the server side when both are stored on the same server, otherwise
+// the content is streamed between them.
+func copyObject(ctx context.Context, from config.Destination, src string, to config.Destination, dst string, opts minio.CopyDestOptions) error {
+	fromClient, err := clientOf(from)
+	if err != nil {
+		return err
+	}
+
+	toClient, err := clientOf(to)
+	if err != nil {
+		return err
+	}
+
+	opts.Bucket, opts.Object = to.Bucket, dst
+	if from.S3Server() == to.S3Server() {
+		info, err := fromClient.StatObject(ctx, from.Bucket, src, minio.StatObjectOptions{})
+		if err != nil {
+			return err
+		}
+		opts.ContentType = cmp.Or(opts.ContentType, info.ContentType)
+		source := minio.CopySrcOptions{Bucket: from.Bucket, Object: src}
+
+		if info.Size <= MAX_COPY_SIZE {
+			_, err = toClient.CopyObject(ctx, opts, source)
+
+			return err
+		}
+
+		// a single copy is limited to 5 GiB, larger objects are copied by
+		// parts. minio-go starts their upload with the user metadata only, so
+		// the content type goes along with it.
+		metadata := info.UserMetadata
+		if opts.ReplaceMetadata {
+			metadata = opts.UserMetadata
+		}
+		opts.UserMetadata = maps.Clone(metadata)
+		if opts.UserMetadata == nil {
+			opts.UserMetadata = map[string]string{}
+		}
+		if opts.ContentType != "" {
+			opts.UserMetadata["Content-Type"] = opts.ContentType
+		}
+		opts.ReplaceMetadata = true
+		_, err = toClient.ComposeObject(ctx, opts, source)
+
+		return err
+	}
+
+	obj, err := fromClient.GetObject(ctx, from.Bucket, src, minio.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = obj.Close() }()
+
+	info, err := obj.Stat()
+	if err != nil {
+		return err
+	}
+
+	putOpts := minio.PutObjectOptions{
+		UserMetadata: info.UserMetadata,
+		ContentType:  cmp.Or(opts.ContentType, info.ContentType),
+	}
+	if opts.ReplaceMetadata {
+		putOpts.UserMetadata = opts.UserMetadata
 	}
 
-	_, err := client.PutObject(ctx, dest.Bucket, path, r, size, options)
+	_, err = toClient.PutObject(ctx, to.Bucket, dst, obj, info.Size, putOpts)
 
 	return err
 }
 
+// MetadataValue looks up a user metadata entry ignoring the "X-Amz-Meta-"
+// prefix and the header canonicalization applied by S3.
+func MetadataValue(metadata map[string]string, name string) string {
+	const metaPrefix = "x-amz-meta-"
+
+	for k, v := range metadata {
+		if len(k) > len(metaPrefix) && strings.EqualFold(k[:len(metaPrefix)], metaPrefix) {
+			k = k[len(metaPrefix):]
+		}
+
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func Stat(ctx context.Context, dest config.Destination, key string) (minio.ObjectInfo, error) {
+	path, err := keyPath(dest, key)
+	if err != nil {
+		return minio.ObjectInfo{}, err
+	}
+
+	return statObject(ctx, dest, path)
+}
+
+// keyPath returns the object path of key, relative to the destination prefix.
+// Keys like "../x" would reach objects out of the prefix, so they are invalid.
+func keyPath(dest config.Destination, key string) (string, error) {
+	if !filepath.IsLocal(key) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	return filepath.Join(dest.Prefix, key), nil
+}
+
+// Update replaces the metadata of an object with params and renames it
+// according to the destination model. It returns the new key, relative to
+// the destination prefix.
+func Update(ctx context.Context, dest config.Destination, key string, params map[string]string) (string, error) {
+	src, err := keyPath(dest, key)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := statObject(ctx, dest, src)
+	if err != nil {
+		return "", err
+	}
+
+	if err := validateParams(dest, params); err != nil {
+		return "", err
+	}
+
+	metadata := maps.Clone(params)
+	metadata["originalFilename"] = cmp.Or(MetadataValue(info.UserMetadata, "originalFilename"), filepath.Base(key))
+	if uploadedBy := MetadataValue(info.UserMetadata, "uploadedBy"); uploadedBy != "" {
+		metadata["uploadedBy"] = uploadedBy
+	}
+
+	dst, err := objectPath(dest, metadata["originalFilename"], metadata)
+	if err != nil {
+		return "", err
+	}
+	if dst != src {
+		if _, err := statObject(ctx, dest, dst); err == nil {
+			return "", fmt.Errorf("%w: %q", ErrObjectExists, dst)
+		}
+	}
+
+	err = copyObject(ctx, dest, src, dest, dst, minio.CopyDestOptions{
+		UserMetadata:    metadata,
+		ReplaceMetadata: true,
+		ContentType:     info.ContentType,
+	})
+	if err != nil {
+		return "", err
+	}
+	forgetUsage(dest)
+	mirror(dest, RelativeKey(dest, dst), false)
+
+	if dst != src {
+		if err := removeObject(ctx, dest, src); err != nil {
+			return "", err
+		}
+		mirror(dest, key, true)
+	}
+
+	return RelativeKey(dest, dst), nil
+}
+
 func List(ctx context.Context, dest config.Destination) ([]minio.ObjectInfo, error) {
-	if _, err := client.BucketExists(ctx, dest.Bucket); err != nil {
+	return ListPrefix(ctx, dest, "")
+}
+
+// ListPrefix lists the objects of a destination under subPrefix, which is
+// relative to the destination prefix.
+func ListPrefix(ctx context.Context, dest config.Destination, subPrefix string) ([]minio.ObjectInfo, error) {
+	c, err := clientOf(dest)
+	if err != nil {
 		return nil, err
 	}
 
-	opts := minio.ListObjectsOptions{Prefix: dest.Prefix, Recursive: true, WithMetadata: true}
-	objCh := client.ListObjects(ctx, dest.Bucket, opts)
+	if _, err := c.BucketExists(ctx, dest.Bucket); err != nil {
+		return nil, err
+	}
+
+	// the prefix is a directory, so "docs" must not match "docs2/…"
+	prefix := ""
+	if dest.Prefix != "" {
+		prefix = filepath.Clean(dest.Prefix) + "/"
+	}
+
+	if subPrefix != "" {
+		prefix = filepath.Join(dest.Prefix, subPrefix) + "/"
+		if strings.HasPrefix(prefix, "../") || (dest.Prefix != "" && !strings.HasPrefix(prefix, filepath.Clean(dest.Prefix)+"/")) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPrefix, subPrefix)
+		}
+	}
+
+	opts := minio.ListObjectsOptions{Prefix: prefix, Recursive: true, WithMetadata: true}
+	objCh := c.ListObjects(ctx, dest.Bucket, opts)
 	list := make([]minio.ObjectInfo, 0)
 	for obj := range objCh {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
 		list = append(list, obj)
 	}
 
@@ -99,9 +684,171 @@ func List(ctx context.Context, dest config.Destination) ([]minio.ObjectInfo, err
 }
 
 func Delete(ctx context.Context, dest config.Destination, key string) error {
-	return client.RemoveObject(
-		ctx,
-		dest.Bucket,
-		filepath.Join(dest.Prefix, key),
-		minio.RemoveObjectOptions{})
+	path, err := keyPath(dest, key)
+	if err != nil {
+		return err
+	}
+
+	if err := removeObject(ctx, dest, path); err != nil {
+		return err
+	}
+	mirror(dest, key, true)
+
+	return nil
+}
+
+// DeleteMultiple removes keys in a single batch request and returns the
+// failures indexed by key.
+func DeleteMultiple(ctx context.Context, dest config.Destination, keys []string) map[string]error {
+	failures := make(map[string]error)
+
+	c, err := clientOf(dest)
+	if err != nil {
+		for _, key := range keys {
+			failures[key] = err
+		}
+
+		return failures
+	}
+
+	objCh := make(chan minio.ObjectInfo, len(keys))
+	for _, key := range keys {
+		path, err := keyPath(dest, key)
+		if err != nil {
+			failures[key] = err
+
+			continue
+		}
+		objCh <- minio.ObjectInfo{Key: path}
+	}
+	close(objCh)
+
+	for e := range c.RemoveObjects(ctx, dest.Bucket, objCh, minio.RemoveObjectsOptions{}) {
+		failures[RelativeKey(dest, e.ObjectName)] = e.Err
+	}
+
+	for _, key := range keys {
+		if failures[key] == nil {
+			uncache(dest, filepath.Join(dest.Prefix, key))
+			mirror(dest, key, true)
+		}
+	}
+
+	return failures
+}
+
+// Move copies an object to another destination, keeping its key relative to
+// the destination prefix and its metadata, and removes the source.
+func Move(ctx context.Context, from, to config.Destination, key string) error {
+	if len(to.AllowedTypes) > 0 {
+		ext := strings.TrimPrefix(filepath.Ext(key), ".")
+		if !slices.Contains(to.AllowedTypes, ext) {
+			return fmt.Errorf("invalid file type: %q", ext)
+		}
+	}
+
+	src, err := keyPath(from, key)
+	if err != nil {
+		return err
+	}
+
+	dst := filepath.Join(to.Prefix, key)
+	if from.Bucket == to.Bucket && from.S3Server() == to.S3Server() && src == dst {
+		return nil
+	}
+
+	if _, err := statObject(ctx, to, dst); err == nil {
+		return fmt.Errorf("%w: %q", ErrObjectExists, dst)
+	}
+
+	info, err := statObject(ctx, from, src)
+	if err != nil {
+		return err
+	}
+
+	_, _, done, err := checkLimits(ctx, to, dst, "", info.Size)
+	if err != nil {
+		return err
+	}
+
+	err = copyObject(ctx, from, src, to, dst, minio.CopyDestOptions{})
+	done(info.Size, err)
+	if err != nil {
+		return err
+	}
+	mirror(to, key, false)
+
+	if err := removeObject(ctx, from, src); err != nil {
+		return err
+	}
+	mirror(from, key, true)
+
+	return nil
+}
+
+func Get(ctx context.Context, dest config.Destination, key string) (*minio.Object, error) {
+	c, err := clientOf(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := keyPath(dest, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetObject(ctx, dest.Bucket, path, minio.GetObjectOptions{})
+}
+
+// GetFrom reads an object starting at offset. When etag is not empty, the
+// read fails if the object has been replaced since.
+func GetFrom(ctx context.Context, dest config.Destination, key string, offset int64, etag string) (*minio.Object, error) {
+	c, err := clientOf(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := minio.GetObjectOptions{}
+	if offset > 0 {
+		if err := opts.SetRange(offset, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if etag != "" {
+		if err := opts.SetMatchETag(etag); err != nil {
+			return nil, err
+		}
+	}
+
+	path, err := keyPath(dest, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetObject(ctx, dest.Bucket, path, opts)
+}
+
+// IsChanged tells if err is the failure of a read by GetFrom because the object
+// has been replaced.
+func IsChanged(err error) bool {
+	resp := minio.ErrorResponse{}
+
+	return errors.As(err, &resp) && resp.StatusCode == http.StatusPreconditionFailed
+}
+
+// PresignedURL returns a link to download an object without credentials,
+// valid for expiry.
+func PresignedURL(ctx context.Context, dest config.Destination, key string, expiry time.Duration) (*url.URL, error) {
+	c, err := clientOf(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := keyPath(dest, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.PresignedGetObject(ctx, dest.Bucket, path, expiry, url.Values{})
 }
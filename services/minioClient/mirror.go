@@ -0,0 +1,185 @@
+package minioClient
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/hitalos/minioUp/config"
+)
+
+const (
+	MIRROR_QUEUE_SIZE   = 1000
+	MIRROR_MAX_ATTEMPTS = 5
+)
+
+type (
+	mirrorJob struct {
+		from   config.Destination
+		to     config.Destination
+		key    string
+		remove bool
+	}
+
+	// Drift is a difference found between a destination and one of its mirrors.
+	Drift struct {
+		Key    string
+		Reason string
+	}
+)
+
+var (
+	// mirrorMu guards mirrorCh, replaced when mirroring starts and stops
+	mirrorMu sync.RWMutex
+	mirrorCh chan mirrorJob
+	mirrorWg = new(sync.WaitGroup)
+)
+
+// StartMirroring starts the workers that replicate changes to the mirrors of
+// the destinations on background. Without them, replication is done before
+// returning from each operation.
+func StartMirroring(workers int) {
+	ch := make(chan mirrorJob, MIRROR_QUEUE_SIZE)
+	mirrorMu.Lock()
+	mirrorCh = ch
+	mirrorMu.Unlock()
+
+	for range max(workers, 1) {
+		mirrorWg.Go(func() {
+			for job := range ch {
+				job.run()
+			}
+		})
+	}
+}
+
+// StopMirroring waits until the queued jobs are done.
+func StopMirroring() {
+	mirrorMu.Lock()
+	ch := mirrorCh
+	mirrorCh = nil
+	mirrorMu.Unlock()
+
+	if ch == nil {
+		return
+	}
+
+	close(ch)
+	mirrorWg.Wait()
+}
+
+// mirror replicates the upload or the removal of key (relative to the
+// destination prefix) to every mirror of dest. When the queue is full, the job
+// is dropped with an error logged instead of blocking the operation; the
+// drift is reported by CheckMirror.
+func mirror(dest config.Destination, key string, remove bool) {
+	if remove && !dest.MirrorDeletes {
+		return
+	}
+
+	mirrorMu.RLock()
+	defer mirrorMu.RUnlock()
+
+	for _, m := range dest.Mirrors() {
+		job := mirrorJob{from: dest, to: m, key: key, remove: remove}
+		if mirrorCh == nil {
+			job.run()
+
+			continue
+		}
+
+		select {
+		case mirrorCh <- job:
+		default:
+			slog.Error("mirror queue full, dropping job", "from", dest.Name, "to", m.Name, "key", key, "remove", remove)
+		}
+	}
+}
+
+func (job mirrorJob) run() {
+	log := slog.With("from", job.from.Name, "to", job.to.Name, "key", job.key, "remove", job.remove)
+
+	for attempt := range MIRROR_MAX_ATTEMPTS {
+		if attempt > 0 {
+			time.Sleep(time.Second << attempt)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		err := job.exec(ctx)
+		cancel()
+		if err == nil {
+			log.Debug("object mirrored")
+
+			return
+		}
+
+		log.Warn("error mirroring object", "error", err, "attempt", attempt+1)
+	}
+
+	log.Error("giving up mirroring object", "attempts", MIRROR_MAX_ATTEMPTS)
+}
+
+func (job mirrorJob) exec(ctx context.Context) error {
+	dst := filepath.Join(job.to.Prefix, job.key)
+	if job.remove {
+		return removeObject(ctx, job.to, dst)
+	}
+
+	if err := copyObject(ctx, job.from, filepath.Join(job.from.Prefix, job.key), job.to, dst, minio.CopyDestOptions{}); err != nil {
+		return err
+	}
+	forgetUsage(job.to)
+
+	return nil
+}
+
+// CheckMirror compares the listing of a destination with one of its mirrors
+// and reports missing, extra and different objects.
+func CheckMirror(ctx context.Context, dest, mirrorDest config.Destination) ([]Drift, error) {
+	primaryList, err := List(ctx, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	mirrorList, err := List(ctx, mirrorDest)
+	if err != nil {
+		return nil, err
+	}
+
+	mirrored := make(map[string]minio.ObjectInfo, len(mirrorList))
+	for _, obj := range mirrorList {
+		mirrored[RelativeKey(mirrorDest, obj.Key)] = obj
+	}
+
+	drifts := []Drift{}
+	for _, obj := range primaryList {
+		key := RelativeKey(dest, obj.Key)
+		m, ok := mirrored[key]
+		delete(mirrored, key)
+
+		switch {
+		case !ok:
+			drifts = append(drifts, Drift{key, "missing"})
+		case m.Size != obj.Size:
+			drifts = append(drifts, Drift{key, "size differs"})
+		case m.LastModified.Before(obj.LastModified):
+			drifts = append(drifts, Drift{key, "outdated"})
+		}
+	}
+
+	if dest.MirrorDeletes {
+		for key := range mirrored {
+			drifts = append(drifts, Drift{key, "extra"})
+		}
+	}
+
+	slices.SortFunc(drifts, func(a, b Drift) int { return strings.Compare(a.Key, b.Key) })
+
+	return drifts, nil
+}
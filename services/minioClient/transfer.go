@@ -0,0 +1,152 @@
+package minioClient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	RESUMABLE_PART_SIZE = 16 << 20 // 16 MB
+	MAX_PARTS           = 10000
+	MAX_COPY_SIZE       = 5 << 30 // 5 GiB, the limit of a single copy
+)
+
+type (
+	// TransferOptions tune an upload. The zero value sends without retries or
+	// progress reporting.
+	TransferOptions struct {
+		// Retries is the number of new attempts after a transient error.
+		Retries int
+		// Resumable sends files over RESUMABLE_PART_SIZE one part at a time,
+		// so a retry resumes from the first part not sent yet. Otherwise,
+		// minio-go sends the parts in parallel and a retry starts over.
+		Resumable bool
+		// Progress is called, possibly concurrently, with the bytes sent.
+		Progress func(n int64)
+	}
+
+	// ProgressFunc is called with the bytes read (by minio-go, as the
+	// progress of PutObjectOptions) or written through it.
+	ProgressFunc func(n int64)
+
+	multipartState struct {
+		id    string
+		parts []minio.CompletePart
+	}
+)
+
+func (p ProgressFunc) Read(b []byte) (int, error) {
+	p(int64(len(b)))
+
+	return len(b), nil
+}
+
+func (p ProgressFunc) Write(b []byte) (int, error) {
+	p(int64(len(b)))
+
+	return len(b), nil
+}
+
+// putObject sends r retrying transient errors with exponential backoff. With
+// transfer.Resumable, files big enough are sent as multipart uploads done part
+// by part, so a retry resumes on the first part not sent yet.
+func putObject(ctx context.Context, c *minio.Client, bucket, key string, r io.Reader, size int64, opts minio.PutObjectOptions, transfer TransferOptions) error {
+	if transfer.Progress != nil {
+		opts.Progress = ProgressFunc(transfer.Progress)
+	}
+
+	ra, isReaderAt := r.(io.ReaderAt)
+	resumable := transfer.Resumable && isReaderAt && transfer.Retries > 0 && size > RESUMABLE_PART_SIZE
+	state := &multipartState{}
+
+	for attempt := 0; ; attempt++ {
+		var err error
+		if resumable {
+			err = putMultipart(ctx, minio.Core{Client: c}, bucket, key, ra, size, opts, transfer.Progress, state)
+		} else {
+			_, err = c.PutObject(ctx, bucket, key, r, size, opts)
+		}
+
+		if err == nil || attempt >= transfer.Retries || !IsTransient(err) {
+			if err != nil && state.id != "" {
+				_ = minio.Core{Client: c}.AbortMultipartUpload(context.WithoutCancel(ctx), bucket, key, state.id)
+			}
+
+			return err
+		}
+
+		// the content must be sent again from the start
+		if !resumable {
+			seeker, ok := r.(io.Seeker)
+			if !ok {
+				return err
+			}
+
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second << attempt):
+		}
+	}
+}
+
+func putMultipart(ctx context.Context, core minio.Core, bucket, key string, r io.ReaderAt, size int64, opts minio.PutObjectOptions, progress func(n int64), state *multipartState) error {
+	if state.id == "" {
+		id, err := core.NewMultipartUpload(ctx, bucket, key, opts)
+		if err != nil {
+			return err
+		}
+		state.id = id
+	}
+
+	partSize := max(RESUMABLE_PART_SIZE, (size+MAX_PARTS-1)/MAX_PARTS)
+	for num := len(state.parts) + 1; int64(num-1)*partSize < size; num++ {
+		offset := int64(num-1) * partSize
+		length := min(partSize, size-offset)
+
+		var body io.Reader = io.NewSectionReader(r, offset, length)
+		if progress != nil {
+			body = io.TeeReader(body, ProgressFunc(progress))
+		}
+
+		part, err := core.PutObjectPart(ctx, bucket, key, state.id, num, body, length, minio.PutObjectPartOptions{})
+		if err != nil {
+			return err
+		}
+
+		state.parts = append(state.parts, minio.CompletePart{PartNumber: num, ETag: part.ETag})
+	}
+
+	_, err := core.CompleteMultipartUpload(ctx, bucket, key, state.id, state.parts, opts)
+
+	return err
+}
+
+// IsTransient tells if err may not happen again on a new attempt, as network
+// errors, S3 throttling or 5xx responses.
+func IsTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrTooLarge) || errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrUserLimitExceeded) {
+		return false
+	}
+
+	if minio.IsNetworkOrHostDown(err, true) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	resp := minio.ToErrorResponse(err)
+	switch resp.Code {
+	case "SlowDown", "RequestTimeout", "InternalError", "ServiceUnavailable", "XMinioServerNotInitialized":
+		return true
+	}
+
+	return resp.StatusCode >= 500
+}
@@ -0,0 +1,212 @@
+package minioClient
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/hitalos/minioUp/config"
+)
+
+// USAGE_CACHE_TTL is how long the listing of a destination, kept to check its
+// quota and user limits, is trusted. The writes and removals made by this
+// process are applied to it meanwhile, so it only misses changes of others.
+const USAGE_CACHE_TTL = time.Minute
+
+type usageCache struct {
+	mu       sync.Mutex
+	objects  map[string]minio.ObjectInfo
+	usage    Usage
+	listedAt time.Time
+}
+
+var (
+	usageCachesMu = new(sync.Mutex)
+	usageCaches   = map[string]*usageCache{}
+)
+
+func usageCacheOf(dest config.Destination) *usageCache {
+	usageCachesMu.Lock()
+	defer usageCachesMu.Unlock()
+
+	key := storageKey(dest)
+	c, ok := usageCaches[key]
+	if !ok {
+		c = &usageCache{}
+		usageCaches[key] = c
+	}
+
+	return c
+}
+
+// storageKey identifies where the objects of a destination are stored, as
+// destinations of different servers may have the same bucket and prefix.
+func storageKey(dest config.Destination) string {
+	return dest.S3Server().Endpoint + "/" + dest.Bucket + "/" + dest.Prefix
+}
+
+// load lists the objects of dest again if the cache expired. It must be
+// called with the cache locked.
+func (c *usageCache) load(ctx context.Context, dest config.Destination) error {
+	if c.objects != nil && time.Since(c.listedAt) < USAGE_CACHE_TTL {
+		return nil
+	}
+
+	list, err := List(ctx, dest)
+	if err != nil {
+		return err
+	}
+
+	c.objects, c.usage = make(map[string]minio.ObjectInfo, len(list)), Usage{}
+	for _, obj := range list {
+		c.set(obj)
+	}
+	c.listedAt = time.Now()
+
+	return nil
+}
+
+func (c *usageCache) set(obj minio.ObjectInfo) {
+	c.remove(obj.Key)
+	c.objects[obj.Key] = obj
+	c.usage.add(obj.Size)
+}
+
+func (c *usageCache) remove(key string) {
+	if old, ok := c.objects[key]; ok {
+		delete(c.objects, key)
+		c.usage.Bytes -= old.Size
+		c.usage.Objects--
+	}
+}
+
+// forgetUsage drops the cached listing of dest after a change that can't be
+// applied to it.
+func forgetUsage(dest config.Destination) {
+	c := usageCacheOf(dest)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.objects = nil
+}
+
+// uncache removes a deleted object from the cached listing of dest.
+func uncache(dest config.Destination, path string) {
+	c := usageCacheOf(dest)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(path)
+}
+
+func hasQuota(dest config.Destination) bool {
+	return dest.Quota != nil && (dest.Quota.MaxBytes > 0 || dest.Quota.MaxObjects > 0)
+}
+
+// checkLimits verifies that writing size bytes at path keeps the destination
+// within its quota and, when username is set, the user within the user limits,
+// returning the bytes each of them still allows. An object being replaced is
+// discounted from the usage, and from the files and bytes of the user if it
+// was uploaded by them. Without a username, as in uploads of the CLI or with no auth
+// driver, the user limits don't apply.
+//
+// The checks of a destination are serialized and the size is counted on the
+// cached usage at once, so concurrent uploads can't exceed the limits together;
+// done must be called with the bytes written or the error of the upload.
+// Streams (of size 0 here) are only bounded by the bytes allowed when they
+// start.
+func checkLimits(ctx context.Context, dest config.Destination, path, username string, size int64) (quota, user int64, done func(written int64, err error), err error) {
+	if !hasQuota(dest) && !(username != "" && hasUserLimits(dest)) {
+		return math.MaxInt64, math.MaxInt64, func(int64, error) {}, nil
+	}
+
+	c := usageCacheOf(dest)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(ctx, dest); err != nil {
+		return 0, 0, nil, err
+	}
+
+	quota, user, err = c.check(dest, path, username, size)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	old, replaced := c.objects[path]
+	metadata := map[string]string{"uploadedBy": username}
+	c.set(minio.ObjectInfo{Key: path, Size: size, LastModified: time.Now(), UserMetadata: metadata})
+
+	return quota, user, func(written int64, err error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		switch {
+		case c.objects == nil:
+		case err == nil:
+			c.set(minio.ObjectInfo{Key: path, Size: written, LastModified: time.Now(), UserMetadata: metadata})
+		case replaced:
+			c.set(old)
+		default:
+			c.remove(path)
+		}
+	}, nil
+}
+
+// previewLimits runs the checks of checkLimits without counting the size.
+func previewLimits(ctx context.Context, dest config.Destination, path, username string, size int64) error {
+	if !hasQuota(dest) && !(username != "" && hasUserLimits(dest)) {
+		return nil
+	}
+
+	c := usageCacheOf(dest)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(ctx, dest); err != nil {
+		return err
+	}
+
+	_, _, err := c.check(dest, path, username, size)
+
+	return err
+}
+
+// check verifies the limits of checkLimits on the cached usage. It must be
+// called with the cache locked and loaded.
+func (c *usageCache) check(dest config.Destination, path, username string, size int64) (quota, user int64, err error) {
+	quota, user = math.MaxInt64, math.MaxInt64
+	old, replaced := c.objects[path]
+
+	if hasQuota(dest) {
+		usage := c.usage
+		if replaced {
+			usage.Bytes -= old.Size
+			usage.Objects--
+		}
+
+		if quota, err = usage.available(dest.Quota, size); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if username != "" && hasUserLimits(dest) {
+		// a replaced object no longer counts, neither its file nor its bytes
+		others := make([]minio.ObjectInfo, 0, len(c.objects))
+		for key, obj := range c.objects {
+			if key != path {
+				others = append(others, obj)
+			}
+		}
+		usage := UserUsageOf(others, username, time.Now())
+
+		if user, err = usage.available(dest.UserLimits, size); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return quota, user, nil
+}
@@ -0,0 +1,61 @@
+package minioClient
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/hitalos/minioUp/config"
+)
+
+func TestUsageCacheCheckUserLimits(t *testing.T) {
+	now := time.Now()
+	lastMonth := now.AddDate(0, -1, -1)
+	uploadedBy := func(username string) map[string]string {
+		return map[string]string{"X-Amz-Meta-Uploadedby": username}
+	}
+
+	objects := map[string]minio.ObjectInfo{
+		"docs/a.pdf":   {Key: "docs/a.pdf", Size: 60, LastModified: now, UserMetadata: uploadedBy("ana")},
+		"docs/old.pdf": {Key: "docs/old.pdf", Size: 500, LastModified: lastMonth, UserMetadata: uploadedBy("ana")},
+		"docs/b.pdf":   {Key: "docs/b.pdf", Size: 90, LastModified: now, UserMetadata: uploadedBy("bia")},
+	}
+
+	tests := []struct {
+		name     string
+		limits   config.UserLimits
+		path     string
+		username string
+		size     int64
+		want     int64
+		wantErr  error
+	}{
+		{"within the day", config.UserLimits{MaxBytesPerDay: 100}, "docs/c.pdf", "ana", 40, 40, nil},
+		{"beyond the day", config.UserLimits{MaxBytesPerDay: 100}, "docs/c.pdf", "ana", 41, 0, ErrUserLimitExceeded},
+		{"replacing own file of today", config.UserLimits{MaxBytesPerDay: 100}, "docs/a.pdf", "ana", 100, 100, nil},
+		{"replacing own file of the month", config.UserLimits{MaxBytesPerMonth: 100}, "docs/a.pdf", "ana", 100, 100, nil},
+		{"replacing own file of a past month", config.UserLimits{MaxBytesPerMonth: 100}, "docs/old.pdf", "ana", 41, 0, ErrUserLimitExceeded},
+		{"replacing a file of another user", config.UserLimits{MaxBytesPerDay: 100}, "docs/b.pdf", "ana", 41, 0, ErrUserLimitExceeded},
+		{"files", config.UserLimits{MaxFiles: 2}, "docs/c.pdf", "ana", 1, 0, ErrUserLimitExceeded},
+		{"replacing own file within the files", config.UserLimits{MaxFiles: 2}, "docs/a.pdf", "ana", 1, math.MaxInt64, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := config.Destination{Prefix: "docs", UserLimits: &tt.limits}
+			c := &usageCache{objects: objects}
+
+			_, user, err := c.check(dest, tt.path, tt.username, tt.size)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("check() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if user != tt.want {
+				t.Errorf("check() user = %d, want %d", user, tt.want)
+			}
+		})
+	}
+}
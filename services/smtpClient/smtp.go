@@ -29,6 +29,18 @@ func SendMail(ctx context.Context, to, subject string, tmpl config.TemplateStrin
 	return m.Send(client)
 }
 
+// Check connects (and authenticates) to the SMTP server without sending any
+// message.
+func Check(ctx context.Context, cfg config.SMTPConfig) error {
+	client, err := smtpConnect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	return client.Quit()
+}
+
 func smtpConnect(ctx context.Context, cfg config.SMTPConfig) (*smtp.Client, error) {
 	var (
 		hostport   = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))